@@ -0,0 +1,292 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acmeChallengeLabel is the label of the TXT records for ACME DNS-01 challenges.
+const acmeChallengeLabel = "_acme-challenge"
+
+// ErrNotPropagated is returned if a challenge record did not become
+// visible within the propagation timeout of the ACMEPolicy.
+var ErrNotPropagated = errors.New("The challenge record was not propagated")
+
+// PropagationVerifier checks whether a TXT record is visible to the ACME server.
+type PropagationVerifier interface {
+	// IsPropagated returns true if a TXT record with the given value is
+	// visible for the given fully qualified name. Errors are treated as
+	// temporary; the verification is repeated until the timeout is reached.
+	IsPropagated(ctx context.Context, fqdn, value string) (bool, error)
+}
+
+// The PropagationVerifierFunc type is an adapter to allow the use of
+// ordinary functions as propagation verifiers.
+type PropagationVerifierFunc func(ctx context.Context, fqdn, value string) (bool, error)
+
+// IsPropagated calls verifierFunc(ctx, fqdn, value).
+func (verifierFunc PropagationVerifierFunc) IsPropagated(ctx context.Context, fqdn, value string) (bool, error) {
+	return verifierFunc(ctx, fqdn, value)
+}
+
+// NewResolverPropagationVerifier creates a PropagationVerifier that looks
+// up the TXT records with the given resolver. If resolver is nil the
+// default resolver is used. Use a resolver that queries the authoritative
+// name servers of DNSimple to avoid waiting for cached negative answers.
+func NewResolverPropagationVerifier(resolver *net.Resolver) PropagationVerifier {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return PropagationVerifierFunc(func(ctx context.Context, fqdn, value string) (bool, error) {
+		values, err := resolver.LookupTXT(ctx, fqdn)
+
+		var dnsError *net.DNSError
+		if errors.As(err, &dnsError) && dnsError.IsNotFound {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		for _, existingValue := range values {
+			if existingValue == value {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// ACMEPolicy defines how challenge records are created and how long
+// the ACMEChallengeSolver waits for them to become visible.
+type ACMEPolicy struct {
+	// TTL is the time to live (in seconds) of the challenge records.
+	TTL int
+
+	// PropagationTimeout is the maximum time to wait for a challenge
+	// record to become visible.
+	PropagationTimeout time.Duration
+
+	// PollingInterval is the delay between two propagation checks.
+	PollingInterval time.Duration
+}
+
+// DefaultACMEPolicy returns a policy that creates challenge records
+// with the minimal TTL and checks every two seconds for up to two
+// minutes whether they are visible.
+func DefaultACMEPolicy() ACMEPolicy {
+	return ACMEPolicy{
+		TTL:                MinTTL,
+		PropagationTimeout: 2 * time.Minute,
+		PollingInterval:    2 * time.Second,
+	}
+}
+
+// ACMEChallengeValue returns the TXT record value for the given key
+// authorization of an ACME DNS-01 challenge (see RFC 8555, section 8.4).
+func ACMEChallengeValue(keyAuthorization string) string {
+	digest := sha256.Sum256([]byte(keyAuthorization))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// ACMEChallengeRecordName returns the name of the challenge record for
+// the given subdomain ("" for the domain itself). Wildcard subdomains
+// (e.g. "*" or "*.www") are validated with the record of their parent.
+func ACMEChallengeRecordName(subdomain string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(subdomain, "*"), ".")
+	if name == "" {
+		return acmeChallengeLabel
+	}
+
+	return acmeChallengeLabel + "." + name
+}
+
+// NewACMEChallengeSolver creates an ACMEChallengeSolver that edits the
+// challenge records with the given editor and waits for them with the
+// given verifier. If verifier is nil Present does not wait.
+func NewACMEChallengeSolver(editor DNSTypedRecordEditor, verifier PropagationVerifier, policy ACMEPolicy) *ACMEChallengeSolver {
+	if policy.TTL == 0 {
+		policy.TTL = MinTTL
+	}
+
+	return &ACMEChallengeSolver{
+		editor:     editor,
+		verifier:   verifier,
+		policy:     policy,
+		challenges: make(map[acmeChallenge]int),
+		sleep:      sleepContext,
+		now:        time.Now,
+	}
+}
+
+// ACMEChallengeSolver presents and cleans up the TXT records of ACME
+// DNS-01 challenges. It is safe for concurrent use.
+//
+// Several challenges can be presented for the same name at once (e.g. for
+// "example.com" and "*.example.com"); each value gets its own TXT record.
+// Presenting the same value more than once is reference counted: the record
+// is created once and removed when the last of its challenges is cleaned up.
+type ACMEChallengeSolver struct {
+	editor   DNSTypedRecordEditor
+	verifier PropagationVerifier
+	policy   ACMEPolicy
+
+	// mutex serializes the changes of the challenge records and
+	// guards the reference counts.
+	mutex      sync.Mutex
+	challenges map[acmeChallenge]int
+
+	sleep func(ctx context.Context, duration time.Duration) error
+	now   func() time.Time
+}
+
+// acmeChallenge identifies a challenge record.
+type acmeChallenge struct {
+	domain string
+	name   string
+	value  string
+}
+
+// Present creates the challenge record with the given value for the given
+// domain and subdomain and waits until it is visible.
+func (solver *ACMEChallengeSolver) Present(domain, subdomain, value string) error {
+	return solver.PresentContext(context.Background(), domain, subdomain, value)
+}
+
+// PresentContext creates the challenge record with the given value for the
+// given domain and subdomain and waits until it is visible. An error
+// matching ErrNotPropagated is returned if the record did not become
+// visible in time. The record might exist even if PresentContext failed,
+// so CleanUp should be called in any case.
+func (solver *ACMEChallengeSolver) PresentContext(ctx context.Context, domain, subdomain, value string) error {
+	challenge := acmeChallenge{normalizeDomain(domain), ACMEChallengeRecordName(subdomain), value}
+	if err := solver.addChallenge(ctx, challenge); err != nil {
+		return err
+	}
+
+	return solver.waitForPropagation(ctx, challenge)
+}
+
+// CleanUp removes the challenge record with the given value for the given
+// domain and subdomain once all challenges that presented it are cleaned up.
+func (solver *ACMEChallengeSolver) CleanUp(domain, subdomain, value string) error {
+	return solver.CleanUpContext(context.Background(), domain, subdomain, value)
+}
+
+// CleanUpContext removes the challenge record with the given value for the
+// given domain and subdomain once all challenges that presented it are
+// cleaned up. Records that do not exist anymore are ignored, errors for
+// unknown domains are returned.
+func (solver *ACMEChallengeSolver) CleanUpContext(ctx context.Context, domain, subdomain, value string) error {
+	challenge := acmeChallenge{normalizeDomain(domain), ACMEChallengeRecordName(subdomain), value}
+
+	solver.mutex.Lock()
+	defer solver.mutex.Unlock()
+
+	// the record is still used by other challenges
+	if solver.challenges[challenge] > 1 {
+		solver.challenges[challenge]--
+		return nil
+	}
+
+	delete(solver.challenges, challenge)
+
+	err := solver.deleteRecord(ctx, challenge)
+	if isRecordNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// addChallenge creates the record of the given challenge unless it
+// was presented already and increments its reference count.
+func (solver *ACMEChallengeSolver) addChallenge(ctx context.Context, challenge acmeChallenge) error {
+	solver.mutex.Lock()
+	defer solver.mutex.Unlock()
+
+	if solver.challenges[challenge] > 0 {
+		solver.challenges[challenge]++
+		return nil
+	}
+
+	// records left over from an earlier attempt are reused
+	err := solver.createRecord(ctx, challenge)
+	if err != nil && !errors.Is(err, ErrAlreadyExists) {
+		return err
+	}
+
+	solver.challenges[challenge] = 1
+	return nil
+}
+
+// waitForPropagation polls the verifier until the record of the given
+// challenge is visible or the propagation timeout is reached.
+func (solver *ACMEChallengeSolver) waitForPropagation(ctx context.Context, challenge acmeChallenge) error {
+	if solver.verifier == nil {
+		return nil
+	}
+
+	fqdn := challenge.name + "." + challenge.domain
+	deadline := solver.now().Add(solver.policy.PropagationTimeout)
+	for {
+		propagated, err := solver.verifier.IsPropagated(ctx, fqdn, challenge.value)
+		if propagated {
+			return nil
+		}
+
+		if !solver.now().Add(solver.policy.PollingInterval).Before(deadline) {
+			if err != nil {
+				return fmt.Errorf("%w: %q is not visible after %s (%s)", ErrNotPropagated, fqdn, solver.policy.PropagationTimeout, err.Error())
+			}
+
+			return fmt.Errorf("%w: %q is not visible after %s", ErrNotPropagated, fqdn, solver.policy.PropagationTimeout)
+		}
+
+		if sleepError := solver.sleep(ctx, solver.policy.PollingInterval); sleepError != nil {
+			return sleepError
+		}
+	}
+}
+
+// createRecord creates the TXT record of the given challenge.
+func (solver *ACMEChallengeSolver) createRecord(ctx context.Context, challenge acmeChallenge) error {
+	record := TXTRecord{Text: challenge.value}
+	if contextEditor, ok := solver.editor.(ContextDNSTypedRecordEditor); ok {
+		_, err := contextEditor.CreateRecordContext(ctx, challenge.domain, challenge.name, solver.policy.TTL, record)
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := solver.editor.CreateRecord(challenge.domain, challenge.name, solver.policy.TTL, record)
+	return err
+}
+
+// deleteRecord deletes the TXT record of the given challenge.
+func (solver *ACMEChallengeSolver) deleteRecord(ctx context.Context, challenge acmeChallenge) error {
+	record := TXTRecord{Text: challenge.value}
+	if contextEditor, ok := solver.editor.(ContextDNSTypedRecordEditor); ok {
+		return contextEditor.DeleteRecordContext(ctx, challenge.domain, challenge.name, record)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return solver.editor.DeleteRecord(challenge.domain, challenge.name, record)
+}
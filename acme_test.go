@@ -0,0 +1,286 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTypedRecordEditor is a DNSTypedRecordEditor whose behavior is defined by functions.
+type testTypedRecordEditor struct {
+	createRecordFunc func(domain, name string, timeToLive int, record TypedRecord) (string, error)
+	updateRecordFunc func(domain, name string, timeToLive int, current, updated TypedRecord) error
+	deleteRecordFunc func(domain, name string, record TypedRecord) error
+}
+
+func (editor *testTypedRecordEditor) CreateRecord(domain, name string, timeToLive int, record TypedRecord) (string, error) {
+	return editor.createRecordFunc(domain, name, timeToLive, record)
+}
+
+func (editor *testTypedRecordEditor) UpdateRecord(domain, name string, timeToLive int, current, updated TypedRecord) error {
+	return editor.updateRecordFunc(domain, name, timeToLive, current, updated)
+}
+
+func (editor *testTypedRecordEditor) DeleteRecord(domain, name string, record TypedRecord) error {
+	return editor.deleteRecordFunc(domain, name, record)
+}
+
+// newTestACMEEditor returns a test editor that keeps the TXT records in
+// the given map (record name and value to TTL).
+func newTestACMEEditor(records map[string]int) *testTypedRecordEditor {
+	var mutex sync.Mutex
+	return &testTypedRecordEditor{
+		createRecordFunc: func(domain, name string, timeToLive int, record TypedRecord) (string, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			key := name + "." + domain + " " + record.Content()
+			if _, exists := records[key]; exists {
+				return "", &RecordExistsError{domain, name, record.Type(), "1"}
+			}
+
+			records[key] = timeToLive
+			return "1", nil
+		},
+		deleteRecordFunc: func(domain, name string, record TypedRecord) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			key := name + "." + domain + " " + record.Content()
+			if _, exists := records[key]; !exists {
+				return &RecordNotFoundError{domain, name, record.Type()}
+			}
+
+			delete(records, key)
+			return nil
+		},
+	}
+}
+
+// ACMEChallengeValue should return the base64url encoded SHA-256 digest of the key authorization.
+func Test_ACMEChallengeValue_DigestIsReturned(t *testing.T) {
+	// arrange
+	keyAuthorization := "evaGxfADs6pSRb2LAv9IZf17Dt3juxGJ-PCt92wr-oA.nP1qzpXGymHBrUEepNY9HCsQk7K8KhOypzEt62jcerQ"
+
+	// act
+	result := ACMEChallengeValue(keyAuthorization)
+
+	// assert
+	if result != "NGwKoXBgCT8JhEa0bK7AwfSqHyu_ZWeugV07fLGIVq0" {
+		t.Fail()
+		t.Logf("ACMEChallengeValue(%q) returned %q", keyAuthorization, result)
+	}
+}
+
+// ACMEChallengeRecordName should prefix the subdomain and strip wildcards.
+func Test_ACMEChallengeRecordName(t *testing.T) {
+	// arrange
+	inputs := map[string]string{
+		"":      "_acme-challenge",
+		"*":     "_acme-challenge",
+		"www":   "_acme-challenge.www",
+		"*.www": "_acme-challenge.www",
+		"a.b":   "_acme-challenge.a.b",
+	}
+
+	for subdomain, expected := range inputs {
+
+		// act
+		result := ACMEChallengeRecordName(subdomain)
+
+		// assert
+		if result != expected {
+			t.Fail()
+			t.Logf("ACMEChallengeRecordName(%q) should return %q but returned %q", subdomain, expected, result)
+		}
+	}
+}
+
+// Present should create the TXT record and wait until the verifier sees it.
+func Test_ACMEChallengeSolver_Present_RecordIsCreatedAndVerified(t *testing.T) {
+	// arrange
+	records := make(map[string]int)
+	var verifiedName string
+	checks := 0
+	verifier := PropagationVerifierFunc(func(ctx context.Context, fqdn, value string) (bool, error) {
+		verifiedName = fqdn + " " + value
+		checks++
+		return checks == 3, nil
+	})
+
+	solver := NewACMEChallengeSolver(newTestACMEEditor(records), verifier, ACMEPolicy{TTL: 120, PropagationTimeout: time.Minute, PollingInterval: time.Second})
+	solver.sleep = func(ctx context.Context, duration time.Duration) error { return nil }
+
+	// act
+	err := solver.Present("example.com", "*.www", "token")
+
+	// assert
+	if err != nil || records["_acme-challenge.www.example.com token"] != 120 {
+		t.Fail()
+		t.Logf("Present() should create the challenge record but returned %v (records: %v)", err, records)
+	}
+
+	if checks != 3 || verifiedName != "_acme-challenge.www.example.com token" {
+		t.Fail()
+		t.Logf("Present() should verify %q until it is visible but checked %q %d times", "_acme-challenge.www.example.com token", verifiedName, checks)
+	}
+}
+
+// Present should return ErrNotPropagated if the record is not visible in time.
+func Test_ACMEChallengeSolver_Present_NotVisible_NotPropagatedErrorIsReturned(t *testing.T) {
+	// arrange
+	currentTime := time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+	verifier := PropagationVerifierFunc(func(ctx context.Context, fqdn, value string) (bool, error) {
+		return false, fmt.Errorf("no such host")
+	})
+
+	solver := NewACMEChallengeSolver(newTestACMEEditor(make(map[string]int)), verifier, ACMEPolicy{PropagationTimeout: 10 * time.Second, PollingInterval: 2 * time.Second})
+	solver.now = func() time.Time { return currentTime }
+	solver.sleep = func(ctx context.Context, duration time.Duration) error {
+		currentTime = currentTime.Add(duration)
+		return nil
+	}
+
+	// act
+	err := solver.Present("example.com", "", "token")
+
+	// assert
+	if !errors.Is(err, ErrNotPropagated) {
+		t.Fail()
+		t.Logf("Present() should return ErrNotPropagated but returned %v", err)
+	}
+}
+
+// Challenges with the same value should share one record until the last one is cleaned up.
+func Test_ACMEChallengeSolver_SameValueTwice_RecordIsReferenceCounted(t *testing.T) {
+	// arrange
+	records := make(map[string]int)
+	creates := 0
+	editor := newTestACMEEditor(records)
+	createRecord := editor.createRecordFunc
+	editor.createRecordFunc = func(domain, name string, timeToLive int, record TypedRecord) (string, error) {
+		creates++
+		return createRecord(domain, name, timeToLive, record)
+	}
+
+	solver := NewACMEChallengeSolver(editor, nil, DefaultACMEPolicy())
+
+	// act
+	firstPresentError := solver.Present("example.com", "", "token")
+	secondPresentError := solver.Present("example.com", "", "token")
+	firstCleanUpError := solver.CleanUp("example.com", "", "token")
+	recordsAfterFirstCleanUp := len(records)
+	secondCleanUpError := solver.CleanUp("example.com", "", "token")
+
+	// assert
+	if firstPresentError != nil || secondPresentError != nil || creates != 1 {
+		t.Fail()
+		t.Logf("Present() should create the record once but created it %d times (%v, %v)", creates, firstPresentError, secondPresentError)
+	}
+
+	if firstCleanUpError != nil || secondCleanUpError != nil || recordsAfterFirstCleanUp != 1 || len(records) != 0 {
+		t.Fail()
+		t.Logf("CleanUp() should remove the record after the last challenge but returned %v, %v (records: %d, %v)", firstCleanUpError, secondCleanUpError, recordsAfterFirstCleanUp, records)
+	}
+}
+
+// Concurrent challenges for the same name should get separate records.
+func Test_ACMEChallengeSolver_ConcurrentChallenges_RecordsAreCreatedAndRemoved(t *testing.T) {
+	// arrange
+	records := make(map[string]int)
+	solver := NewACMEChallengeSolver(newTestACMEEditor(records), nil, DefaultACMEPolicy())
+	values := []string{"token-1", "token-2", "token-3", "token-4"}
+
+	// act
+	presentErrors := make([]error, len(values))
+	var wait sync.WaitGroup
+	for index, value := range values {
+		wait.Add(1)
+		go func(index int, value string) {
+			defer wait.Done()
+			presentErrors[index] = solver.Present("example.com", "*", value)
+		}(index, value)
+	}
+
+	wait.Wait()
+	recordsAfterPresent := len(records)
+
+	for _, value := range values {
+		if err := solver.CleanUp("example.com", "", value); err != nil {
+			t.Fail()
+			t.Logf("CleanUp(%q) returned an error: %s", value, err.Error())
+		}
+	}
+
+	// assert
+	for index, err := range presentErrors {
+		if err != nil {
+			t.Fail()
+			t.Logf("Present(%q) returned an error: %s", values[index], err.Error())
+		}
+	}
+
+	if recordsAfterPresent != len(values) || len(records) != 0 {
+		t.Fail()
+		t.Logf("The solver should create %d records and remove them all but created %d (remaining: %v)", len(values), recordsAfterPresent, records)
+	}
+}
+
+// Records left over from an earlier attempt should be reused and removed.
+func Test_ACMEChallengeSolver_RecordExists_RecordIsReused(t *testing.T) {
+	// arrange
+	records := map[string]int{"_acme-challenge.example.com token": 60}
+	solver := NewACMEChallengeSolver(newTestACMEEditor(records), nil, DefaultACMEPolicy())
+
+	// act
+	presentError := solver.Present("example.com", "", "token")
+	cleanUpError := solver.CleanUp("example.com", "", "token")
+
+	// assert
+	if presentError != nil || cleanUpError != nil || len(records) != 0 {
+		t.Fail()
+		t.Logf("The solver should reuse and remove the existing record but returned %v, %v (records: %v)", presentError, cleanUpError, records)
+	}
+}
+
+// CleanUp should ignore records that do not exist.
+func Test_ACMEChallengeSolver_CleanUp_RecordNotFound_NoErrorIsReturned(t *testing.T) {
+	// arrange
+	solver := NewACMEChallengeSolver(newTestACMEEditor(make(map[string]int)), nil, DefaultACMEPolicy())
+
+	// act
+	err := solver.CleanUp("example.com", "www", "token")
+
+	// assert
+	if err != nil {
+		t.Fail()
+		t.Logf("CleanUp() should ignore missing records but returned %v", err)
+	}
+}
+
+// CleanUp should return errors for unknown domains.
+func Test_ACMEChallengeSolver_CleanUp_DomainNotFound_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := newTestACMEEditor(make(map[string]int))
+	editor.deleteRecordFunc = func(domain, name string, record TypedRecord) error {
+		return &APIError{StatusCode: 404, Status: "404 Not Found", Message: "Domain not found"}
+	}
+
+	solver := NewACMEChallengeSolver(editor, nil, DefaultACMEPolicy())
+
+	// act
+	err := solver.CleanUp("example.com", "www", "token")
+
+	// assert
+	if !errors.Is(err, ErrNotFound) {
+		t.Fail()
+		t.Logf("CleanUp() should return the not found error of the domain but returned %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"sync"
+	"time"
+)
+
+// CacheInvalidator is implemented by info providers that cache DNS
+// records. The DNSEditor invalidates the cached records of a domain
+// after every change it makes to the domain.
+type CacheInvalidator interface {
+	// Invalidate removes all cached records of the given domain.
+	Invalidate(domain string)
+
+	// InvalidateAll removes all cached records and domain names.
+	InvalidateAll()
+}
+
+// NewCachingDNSInfoProvider creates an info provider that caches the
+// results of the given info provider for the given time to live.
+// Errors are not cached.
+func NewCachingDNSInfoProvider(infoProvider DNSInfoProvider, ttl time.Duration) *CachingDNSInfoProvider {
+	return &CachingDNSInfoProvider{
+		infoProvider: AsContextDNSInfoProvider(infoProvider),
+		ttl:          ttl,
+		domainTTLs:   make(map[string]time.Duration),
+		domains:      make(map[string]*domainCache),
+		now:          time.Now,
+	}
+}
+
+// CachingDNSInfoProvider caches the DNS records and domain names of a
+// DNSInfoProvider. It implements the DNSInfoProvider, the
+// ContextDNSInfoProvider and the CacheInvalidator interface.
+type CachingDNSInfoProvider struct {
+	infoProvider ContextDNSInfoProvider
+	ttl          time.Duration
+
+	lock        sync.Mutex
+	domainTTLs  map[string]time.Duration
+	domains     map[string]*domainCache
+	domainNames cacheEntry
+
+	// domainNamesGeneration is increased by InvalidateAll so that domain
+	// names requested before the invalidation are not cached
+	domainNamesGeneration uint64
+
+	now func() time.Time
+}
+
+// domainCache contains the cached records of a domain.
+type domainCache struct {
+	records    cacheEntry
+	subdomains map[string]cacheEntry
+
+	// generation is increased by every invalidation of the domain so
+	// that results of requests that started before the invalidation
+	// are not cached
+	generation uint64
+}
+
+// invalidate removes the cached records of the domain.
+func (entry *domainCache) invalidate() {
+	entry.records = cacheEntry{}
+	entry.subdomains = make(map[string]cacheEntry)
+	entry.generation++
+}
+
+// cacheEntry is a cached result.
+type cacheEntry struct {
+	records   []dnsimple.Record
+	names     []string
+	expiresAt time.Time
+	valid     bool
+}
+
+// isFresh returns true if the entry is valid at the given time.
+func (entry cacheEntry) isFresh(now time.Time) bool {
+	return entry.valid && now.Before(entry.expiresAt)
+}
+
+// SetDomainTTL sets the time to live of the cached records of the
+// given domain. It replaces the default time to live for that domain.
+func (cache *CachingDNSInfoProvider) SetDomainTTL(domain string, ttl time.Duration) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.domainTTLs[normalizeDomain(domain)] = ttl
+}
+
+// Invalidate removes all cached records of the given domain.
+func (cache *CachingDNSInfoProvider) Invalidate(domain string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if entry, ok := cache.domains[normalizeDomain(domain)]; ok {
+		entry.invalidate()
+	}
+}
+
+// InvalidateAll removes all cached records and domain names.
+func (cache *CachingDNSInfoProvider) InvalidateAll() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	for _, entry := range cache.domains {
+		entry.invalidate()
+	}
+
+	cache.domainNames = cacheEntry{}
+	cache.domainNamesGeneration++
+}
+
+// GetDomainNames returns a list of all available domain names.
+func (cache *CachingDNSInfoProvider) GetDomainNames() ([]string, error) {
+	return cache.GetDomainNamesContext(context.Background())
+}
+
+// GetDomainRecords returns all DNS records for the given domain.
+func (cache *CachingDNSInfoProvider) GetDomainRecords(domain string) ([]dnsimple.Record, error) {
+	return cache.GetDomainRecordsContext(context.Background(), domain)
+}
+
+// GetSubdomainRecord return the subdomain record that matches the given name and record type.
+func (cache *CachingDNSInfoProvider) GetSubdomainRecord(domain, subdomain, recordType string) (dnsimple.Record, error) {
+	return cache.GetSubdomainRecordContext(context.Background(), domain, subdomain, recordType)
+}
+
+// GetSubdomainRecords returns all DNS records for the given subdomain.
+func (cache *CachingDNSInfoProvider) GetSubdomainRecords(domain, subdomain string) ([]dnsimple.Record, error) {
+	return cache.GetSubdomainRecordsContext(context.Background(), domain, subdomain)
+}
+
+// GetDomainNamesContext returns a list of all available domain names.
+func (cache *CachingDNSInfoProvider) GetDomainNamesContext(ctx context.Context) ([]string, error) {
+	cache.lock.Lock()
+	if cache.domainNames.isFresh(cache.now()) {
+		defer cache.lock.Unlock()
+		return copyStrings(cache.domainNames.names), nil
+	}
+	generation := cache.domainNamesGeneration
+	cache.lock.Unlock()
+
+	names, err := cache.infoProvider.GetDomainNamesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if generation == cache.domainNamesGeneration {
+		cache.domainNames = cacheEntry{names: copyStrings(names), expiresAt: cache.now().Add(cache.ttl), valid: true}
+	}
+
+	return names, nil
+}
+
+// GetDomainRecordsContext returns all DNS records for the given domain.
+func (cache *CachingDNSInfoProvider) GetDomainRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	cache.lock.Lock()
+	domainEntry := cache.domain(domain)
+	if domainEntry.records.isFresh(cache.now()) {
+		defer cache.lock.Unlock()
+		return copyRecords(domainEntry.records.records), nil
+	}
+	generation := domainEntry.generation
+	cache.lock.Unlock()
+
+	records, err := cache.infoProvider.GetDomainRecordsContext(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if generation == domainEntry.generation {
+		domainEntry.records = cache.newEntry(domain, records)
+	}
+
+	return records, nil
+}
+
+// GetSubdomainRecordContext return the subdomain record that matches the given name and record type.
+// If no matching subdomain was found or an error occurred while fetching the available records
+// an error will be returned.
+func (cache *CachingDNSInfoProvider) GetSubdomainRecordContext(ctx context.Context, domain, subdomain, recordType string) (dnsimple.Record, error) {
+	records, err := cache.GetSubdomainRecordsContext(ctx, domain, subdomain)
+	if err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	for _, record := range records {
+		if record.RecordType == recordType {
+			return record, nil
+		}
+	}
+
+	return dnsimple.Record{}, &RecordNotFoundError{domain, subdomain, recordType}
+}
+
+// GetSubdomainRecordsContext returns all DNS records for the given subdomain.
+// The records are taken from the cached records of the domain if they are available.
+func (cache *CachingDNSInfoProvider) GetSubdomainRecordsContext(ctx context.Context, domain, subdomain string) ([]dnsimple.Record, error) {
+	cache.lock.Lock()
+	now := cache.now()
+	domainEntry := cache.domain(domain)
+	if domainEntry.records.isFresh(now) {
+		defer cache.lock.Unlock()
+		return RecordFilter{Name: subdomain}.apply(domainEntry.records.records), nil
+	}
+
+	if entry := domainEntry.subdomains[subdomain]; entry.isFresh(now) {
+		defer cache.lock.Unlock()
+		return copyRecords(entry.records), nil
+	}
+	generation := domainEntry.generation
+	cache.lock.Unlock()
+
+	records, err := cache.infoProvider.GetSubdomainRecordsContext(ctx, domain, subdomain)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if generation == domainEntry.generation {
+		domainEntry.subdomains[subdomain] = cache.newEntry(domain, records)
+	}
+
+	return records, nil
+}
+
+// domain returns the cache of the given domain. The entries are kept
+// when they are invalidated so that their generation is preserved.
+// The lock must be held.
+func (cache *CachingDNSInfoProvider) domain(domain string) *domainCache {
+	key := normalizeDomain(domain)
+	entry, ok := cache.domains[key]
+	if !ok {
+		entry = &domainCache{subdomains: make(map[string]cacheEntry)}
+		cache.domains[key] = entry
+	}
+
+	return entry
+}
+
+// newEntry creates a cache entry for the given records of the given
+// domain. The lock must be held.
+func (cache *CachingDNSInfoProvider) newEntry(domain string, records []dnsimple.Record) cacheEntry {
+	ttl, ok := cache.domainTTLs[normalizeDomain(domain)]
+	if !ok {
+		ttl = cache.ttl
+	}
+
+	return cacheEntry{records: copyRecords(records), expiresAt: cache.now().Add(ttl), valid: true}
+}
+
+// copyRecords returns a copy of the given records so that callers
+// cannot modify the cache.
+func copyRecords(records []dnsimple.Record) []dnsimple.Record {
+	if records == nil {
+		return nil
+	}
+
+	return append([]dnsimple.Record(nil), records...)
+}
+
+// copyStrings returns a copy of the given strings.
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	return append([]string(nil), values...)
+}
@@ -0,0 +1,242 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestCachingDNSInfoProvider creates a caching info provider with the given
+// TTL whose clock can be advanced with the returned function.
+func newTestCachingDNSInfoProvider(infoProvider DNSInfoProvider, ttl time.Duration) (*CachingDNSInfoProvider, func(time.Duration)) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewCachingDNSInfoProvider(infoProvider, ttl)
+	cache.now = func() time.Time {
+		return now
+	}
+
+	return cache, func(duration time.Duration) {
+		now = now.Add(duration)
+	}
+}
+
+// newCountingInfoProvider returns an info provider that returns a www record
+// and counts the calls of GetSubdomainRecords.
+func newCountingInfoProvider(calls *int) testDNSInfoProvider {
+	return testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			*calls++
+			return []dnsimple.Record{{Id: 1, Name: subdomain, RecordType: "A", Content: "127.0.0.1"}}, nil
+		},
+	}
+}
+
+// Records should be served from the cache until the TTL expires.
+func Test_CachingDNSInfoProvider_GetSubdomainRecord_RecordsAreCachedForTTL(t *testing.T) {
+	// arrange
+	calls := 0
+	cache, advance := newTestCachingDNSInfoProvider(newCountingInfoProvider(&calls), time.Minute)
+
+	// act
+	cache.GetSubdomainRecord("example.com", "www", "A")
+	advance(59 * time.Second)
+	cache.GetSubdomainRecord("example.com", "www", "A")
+	callsWithinTTL := calls
+
+	advance(time.Second)
+	cache.GetSubdomainRecord("example.com", "www", "A")
+
+	// assert
+	if callsWithinTTL != 1 || calls != 2 {
+		t.Fail()
+		t.Logf("The wrapped provider should be called once within the TTL and again after it expired but was called %d and %d times", callsWithinTTL, calls)
+	}
+}
+
+// The TTL of a domain should replace the default TTL.
+func Test_CachingDNSInfoProvider_SetDomainTTL_DomainTTLIsUsed(t *testing.T) {
+	// arrange
+	calls := 0
+	cache, advance := newTestCachingDNSInfoProvider(newCountingInfoProvider(&calls), time.Hour)
+	cache.SetDomainTTL("example.com", 30*time.Second)
+
+	// act
+	cache.GetSubdomainRecord("example.com", "www", "A")
+	advance(30 * time.Second)
+	cache.GetSubdomainRecord("example.com", "www", "A")
+
+	// assert
+	if calls != 2 {
+		t.Fail()
+		t.Logf("The domain TTL should have expired but the wrapped provider was called %d times", calls)
+	}
+}
+
+// Missing record types should be reported from the cached records of the subdomain.
+func Test_CachingDNSInfoProvider_GetSubdomainRecord_TypeNotCached_NotFoundErrorIsReturned(t *testing.T) {
+	// arrange
+	calls := 0
+	cache, _ := newTestCachingDNSInfoProvider(newCountingInfoProvider(&calls), time.Minute)
+
+	// act
+	cache.GetSubdomainRecord("example.com", "www", "A")
+	_, err := cache.GetSubdomainRecord("example.com", "www", "AAAA")
+
+	// assert
+	if _, ok := err.(*RecordNotFoundError); !ok || calls != 1 {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return a RecordNotFoundError from the cache but returned %v after %d calls", err, calls)
+	}
+}
+
+// Invalidate should remove the cached records of the given domain only.
+func Test_CachingDNSInfoProvider_Invalidate_DomainIsReloaded(t *testing.T) {
+	// arrange
+	calls := 0
+	cache, _ := newTestCachingDNSInfoProvider(newCountingInfoProvider(&calls), time.Minute)
+	cache.GetSubdomainRecord("example.com", "www", "A")
+	cache.GetSubdomainRecord("example.org", "www", "A")
+
+	// act
+	cache.Invalidate("Example.com.")
+	cache.GetSubdomainRecord("example.com", "www", "A")
+	cache.GetSubdomainRecord("example.org", "www", "A")
+
+	// assert
+	if calls != 3 {
+		t.Fail()
+		t.Logf("Only example.com should have been reloaded but the wrapped provider was called %d times", calls)
+	}
+}
+
+// Invalidating a domain should only discard the results of requests for that domain that are in flight.
+func Test_CachingDNSInfoProvider_InvalidateDuringRequest_OnlyResultsOfDomainAreDiscarded(t *testing.T) {
+	// arrange
+	calls := make(map[string]int)
+	started := make(chan bool)
+	release := make(chan bool)
+	blocking := true
+	cache, _ := newTestCachingDNSInfoProvider(testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			calls[domain]++
+			if blocking {
+				started <- true
+				<-release
+			}
+
+			return []dnsimple.Record{{Id: 1, Name: subdomain, RecordType: "A", Content: "127.0.0.1"}}, nil
+		},
+	}, time.Minute)
+
+	done := make(chan bool)
+	for _, domain := range []string{"example.com", "example.org"} {
+		go func(domain string) {
+			cache.GetSubdomainRecord(domain, "www", "A")
+			done <- true
+		}(domain)
+		<-started
+	}
+
+	// act
+	cache.Invalidate("example.com")
+	release <- true
+	release <- true
+	<-done
+	<-done
+
+	blocking = false
+	cache.GetSubdomainRecord("example.com", "www", "A")
+	cache.GetSubdomainRecord("example.org", "www", "A")
+
+	// assert
+	if calls["example.com"] != 2 || calls["example.org"] != 1 {
+		t.Fail()
+		t.Logf("Only the result for example.com should have been discarded but the wrapped provider was called %v times", calls)
+	}
+}
+
+// Errors of the wrapped provider must not be cached.
+func Test_CachingDNSInfoProvider_WrappedProviderFails_ErrorIsNotCached(t *testing.T) {
+	// arrange
+	calls := 0
+	cache, _ := newTestCachingDNSInfoProvider(testDNSInfoProvider{
+		getDomainNamesFunc: func() ([]string, error) {
+			calls++
+			if calls == 1 {
+				return nil, fmt.Errorf("Temporary error")
+			}
+
+			return []string{"example.com"}, nil
+		},
+	}, time.Minute)
+
+	// act
+	cache.GetDomainNames()
+	names, err := cache.GetDomainNames()
+
+	// assert
+	if err != nil || len(names) != 1 || calls != 2 {
+		t.Fail()
+		t.Logf("The second call should have reached the wrapped provider but returned %v, %v after %d calls", names, err, calls)
+	}
+}
+
+// Subdomain queries should be answered from cached domain records.
+func Test_CachingDNSInfoProvider_DomainRecordsCached_SubdomainIsServedFromCache(t *testing.T) {
+	// arrange
+	calls := 0
+	cache, _ := newTestCachingDNSInfoProvider(testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: "www", RecordType: "A"}, {Id: 2, Name: "mail", RecordType: "A"}}, nil
+		},
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			calls++
+			return nil, nil
+		},
+	}, time.Minute)
+
+	// act
+	cache.GetDomainRecords("example.com")
+	record, err := cache.GetSubdomainRecord("example.com", "mail", "A")
+
+	// assert
+	if err != nil || record.Id != 2 || calls != 0 {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return the cached record 2 but returned %v, %v after %d calls", record, err, calls)
+	}
+}
+
+// Updates made with the DNSEditor should invalidate the cached records of the domain.
+func Test_DNSEditor_UpdateSubdomain_CachingInfoProvider_CacheIsInvalidated(t *testing.T) {
+	// arrange
+	content := "127.0.0.1"
+	client := &testDNSClient{
+		getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: content, Ttl: 600}}, nil
+		},
+		updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+			content = opts.Value
+			return id, nil
+		},
+	}
+
+	cache := NewCachingDNSInfoProvider(NewDNSInfoProvider(client), time.Hour)
+	editor := NewDNSEditor(client, cache)
+	cache.GetSubdomainRecord("example.com", "www", "A")
+
+	// act
+	editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.2"))
+	record, _ := cache.GetSubdomainRecord("example.com", "www", "A")
+
+	// assert
+	if record.Content != "127.0.0.2" {
+		t.Fail()
+		t.Logf("The cache should return the updated record but returned %v", record)
+	}
+}
@@ -6,6 +6,7 @@ package deens
 
 import (
 	"fmt"
+	"strings"
 )
 
 // NewAPICredentials creates a new credentials model from the given
@@ -32,6 +33,106 @@ type APICredentials struct {
 	Token string
 }
 
+// Kind returns AccountCredentialKind.
+func (credentials APICredentials) Kind() CredentialKind {
+	return AccountCredentialKind
+}
+
+// NewDomainTokenCredentials creates a new credentials model from the
+// given domain name and domain API token. If the given parameters are
+// invalid an error will be returned.
+func NewDomainTokenCredentials(domain, token string) (DomainTokenCredentials, error) {
+	if !isValidDomain(domain) {
+		return DomainTokenCredentials{}, fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isEmpty(token) {
+		return DomainTokenCredentials{}, fmt.Errorf("No domain token given")
+	}
+
+	return DomainTokenCredentials{domain, token}, nil
+}
+
+// DomainTokenCredentials contains a domain token that grants access
+// to a single domain only.
+type DomainTokenCredentials struct {
+	// Domain is the name of the domain the token belongs to
+	Domain string
+
+	// Token is the domain API token
+	Token string
+}
+
+// Kind returns DomainTokenCredentialKind.
+func (credentials DomainTokenCredentials) Kind() CredentialKind {
+	return DomainTokenCredentialKind
+}
+
+// NewAccessTokenCredentials creates a new credentials model for the
+// DNSimple API v2 from the given account ID and OAuth or account access
+// token. The account ID can be empty; it is then determined from the
+// token. If the given token is empty an error will be returned.
+func NewAccessTokenCredentials(accountID, token string) (AccessTokenCredentials, error) {
+	if isEmpty(token) {
+		return AccessTokenCredentials{}, fmt.Errorf("No access token given")
+	}
+
+	return AccessTokenCredentials{strings.TrimSpace(accountID), token}, nil
+}
+
+// AccessTokenCredentials contains the credentials for accessing the
+// DNSimple API v2.
+type AccessTokenCredentials struct {
+	// AccountID is the ID of the DNSimple account. If it is empty the
+	// account the token belongs to is used.
+	AccountID string
+
+	// Token is the access token that is sent as bearer token
+	Token string
+}
+
+// Kind returns AccessTokenCredentialKind.
+func (credentials AccessTokenCredentials) Kind() CredentialKind {
+	return AccessTokenCredentialKind
+}
+
+// CredentialKind identifies the type of credentials.
+type CredentialKind int
+
+const (
+	// AccountCredentialKind identifies account-wide credentials
+	// (e-mail address and API token).
+	AccountCredentialKind CredentialKind = iota
+
+	// DomainTokenCredentialKind identifies credentials that are
+	// restricted to a single domain.
+	DomainTokenCredentialKind
+
+	// AccessTokenCredentialKind identifies access tokens for the
+	// DNSimple API v2.
+	AccessTokenCredentialKind
+)
+
+func (kind CredentialKind) String() string {
+	switch kind {
+	case AccountCredentialKind:
+		return "account"
+	case DomainTokenCredentialKind:
+		return "domain token"
+	case AccessTokenCredentialKind:
+		return "access token"
+	}
+
+	return fmt.Sprintf("CredentialKind(%d)", int(kind))
+}
+
+// Credentials are accepted by NewDNSClient. They are implemented by
+// APICredentials, DomainTokenCredentials and AccessTokenCredentials.
+type Credentials interface {
+	// Kind returns the type of the credentials.
+	Kind() CredentialKind
+}
+
 // CredentialProvider returns credentials.
 type CredentialProvider interface {
 	// GetCredentials returns any stored credentials if there are any.
@@ -0,0 +1,353 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// encryptedCredentialsFileName is the name of the file that is used by
+// the EncryptedCredentialStore if no explicit path is given.
+const encryptedCredentialsFileName = "credentials.enc"
+
+// encryptedCredentialsVersion is the version of the on-disk format
+// that is written by the EncryptedCredentialStore.
+const encryptedCredentialsVersion = 1
+
+// Key derivation functions supported by the on-disk format.
+const (
+	kdfPBKDF2SHA256 = "pbkdf2-sha256"
+	kdfHKDFSHA256   = "hkdf-sha256"
+)
+
+// defaultPBKDF2Iterations is the number of PBKDF2 iterations that are
+// used for new passphrase-protected credential files.
+const defaultPBKDF2Iterations = 600000
+
+// minimumKeyFileSize is the minimum number of bytes a key file must contain.
+const minimumKeyFileSize = 32
+
+// ErrInvalidCredentialKey is returned if the credentials cannot be
+// decrypted with the given key. Either the key is wrong or the
+// credentials file has been tampered with.
+var ErrInvalidCredentialKey = errors.New("Unable to decrypt credentials. The key is invalid or the file was modified")
+
+// ErrUnsupportedCredentialsFormat is returned if the encrypted
+// credentials file was written in a format this version of dee-ns
+// does not understand.
+var ErrUnsupportedCredentialsFormat = errors.New("Unsupported credentials file format")
+
+// CredentialKey is the secret that is used to encrypt credentials.
+// Use PassphraseKey or KeyFileKey to create one.
+type CredentialKey struct {
+	passphrase  string
+	keyFilePath string
+}
+
+// PassphraseKey returns a CredentialKey that derives the encryption
+// key from the given passphrase using PBKDF2-SHA256.
+func PassphraseKey(passphrase string) CredentialKey {
+	return CredentialKey{passphrase: passphrase}
+}
+
+// KeyFileKey returns a CredentialKey that derives the encryption key
+// from the content of the given file using HKDF-SHA256. The key file
+// must contain at least 32 bytes and must only be readable by its owner.
+func KeyFileKey(path string) CredentialKey {
+	return CredentialKey{keyFilePath: path}
+}
+
+// validate returns an error if the key is not usable.
+func (key CredentialKey) validate() error {
+	if isEmpty(key.passphrase) && isEmpty(key.keyFilePath) {
+		return fmt.Errorf("No passphrase or key file given")
+	}
+
+	return nil
+}
+
+// kdf returns the name of the key derivation function for this key.
+func (key CredentialKey) kdf() string {
+	if key.keyFilePath != "" {
+		return kdfHKDFSHA256
+	}
+
+	return kdfPBKDF2SHA256
+}
+
+// derive returns the AES-256 key for the given file header.
+func (key CredentialKey) derive(header encryptedCredentialsHeader) ([]byte, error) {
+	if header.KDF != key.kdf() {
+		return nil, fmt.Errorf("The credentials were encrypted with %q but the given key uses %q: %w", header.KDF, key.kdf(), ErrInvalidCredentialKey)
+	}
+
+	switch header.KDF {
+	case kdfPBKDF2SHA256:
+		if header.Iterations < 1 {
+			return nil, fmt.Errorf("Invalid number of iterations %d: %w", header.Iterations, ErrUnsupportedCredentialsFormat)
+		}
+
+		return pbkdf2.Key(sha256.New, key.passphrase, header.Salt, header.Iterations, 32)
+
+	case kdfHKDFSHA256:
+		secret, err := readPrivateFile(key.keyFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read key file: %w", err)
+		}
+
+		if len(secret) < minimumKeyFileSize {
+			return nil, fmt.Errorf("The key file %q must contain at least %d bytes", key.keyFilePath, minimumKeyFileSize)
+		}
+
+		return hkdf.Key(sha256.New, secret, header.Salt, "dee-ns credentials", 32)
+	}
+
+	return nil, fmt.Errorf("Unknown key derivation function %q: %w", header.KDF, ErrUnsupportedCredentialsFormat)
+}
+
+// encryptedCredentialsHeader contains the unencrypted parameters of an
+// encrypted credentials file. The header is authenticated together
+// with the ciphertext.
+type encryptedCredentialsHeader struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+}
+
+// encryptedCredentialsFile defines the on-disk format of an encrypted
+// credentials file.
+type encryptedCredentialsFile struct {
+	encryptedCredentialsHeader
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// additionalData returns the data that is authenticated but not
+// encrypted by AES-GCM.
+func (header encryptedCredentialsHeader) additionalData() []byte {
+	return []byte(fmt.Sprintf("dee-ns/v%d/%s/%d/%x", header.Version, header.KDF, header.Iterations, header.Salt))
+}
+
+// NewEncryptedCredentialStore creates a new CredentialStore that keeps
+// the credentials encrypted with AES-256-GCM in the file with the given
+// path. If the path is empty the file is stored next to the
+// DefaultCredentialsFilePath.
+func NewEncryptedCredentialStore(path string, key CredentialKey) (*EncryptedCredentialStore, error) {
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+
+	if isEmpty(path) {
+		defaultPath, err := DefaultCredentialsFilePath()
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Join(filepath.Dir(defaultPath), encryptedCredentialsFileName)
+	}
+
+	return &EncryptedCredentialStore{path, key, defaultPBKDF2Iterations}, nil
+}
+
+// EncryptedCredentialStore reads and persists APICredentials in an
+// encrypted file that is only accessible by the current user.
+type EncryptedCredentialStore struct {
+	path       string
+	key        CredentialKey
+	iterations int
+}
+
+// Path returns the path of the encrypted credentials file.
+func (store *EncryptedCredentialStore) Path() string {
+	return store.path
+}
+
+// GetCredentials decrypts and returns the stored credentials.
+// Returns ErrNoCredentials if the file does not exist and
+// ErrInvalidCredentialKey if the credentials cannot be decrypted.
+func (store *EncryptedCredentialStore) GetCredentials() (APICredentials, error) {
+	content, err := readPrivateFile(store.path)
+	if os.IsNotExist(err) {
+		return APICredentials{}, ErrNoCredentials
+	}
+
+	if err != nil {
+		return APICredentials{}, err
+	}
+
+	var file encryptedCredentialsFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return APICredentials{}, fmt.Errorf("Unable to read credentials from %q: %s", store.path, err.Error())
+	}
+
+	// older formats are handled here once the format changes
+	if file.Version != encryptedCredentialsVersion {
+		return APICredentials{}, fmt.Errorf("%q has version %d: %w", store.path, file.Version, ErrUnsupportedCredentialsFormat)
+	}
+
+	plaintext, err := decryptCredentials(store.key, file)
+	if err != nil {
+		return APICredentials{}, err
+	}
+
+	var credentials credentialsFile
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return APICredentials{}, fmt.Errorf("Unable to read credentials from %q: %s", store.path, err.Error())
+	}
+
+	return NewAPICredentials(credentials.Email, credentials.Token)
+}
+
+// SaveCredentials encrypts the given credentials with a fresh salt and
+// nonce and atomically replaces the credentials file.
+func (store *EncryptedCredentialStore) SaveCredentials(credentials APICredentials) error {
+	return store.save(store.key, credentials)
+}
+
+// DeleteCredentials removes the encrypted credentials file. It is not
+// an error if the file does not exist.
+func (store *EncryptedCredentialStore) DeleteCredentials() error {
+	if err := os.Remove(store.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to delete credentials file %q: %s", store.path, err.Error())
+	}
+
+	return nil
+}
+
+// Rekey decrypts the stored credentials with the current key and
+// encrypts them with the given new key. The file is always written in
+// the latest format version. The store uses the new key afterwards.
+func (store *EncryptedCredentialStore) Rekey(newKey CredentialKey) error {
+	if err := newKey.validate(); err != nil {
+		return err
+	}
+
+	credentials, err := store.GetCredentials()
+	if err != nil {
+		return err
+	}
+
+	if err := store.save(newKey, credentials); err != nil {
+		return err
+	}
+
+	store.key = newKey
+	return nil
+}
+
+// save encrypts the given credentials with the given key and writes them
+// to the credentials file.
+func (store *EncryptedCredentialStore) save(key CredentialKey, credentials APICredentials) error {
+	if _, err := NewAPICredentials(credentials.Email, credentials.Token); err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(credentialsFile{credentials.Email, credentials.Token})
+	if err != nil {
+		return fmt.Errorf("Unable to serialize credentials: %s", err.Error())
+	}
+
+	header := encryptedCredentialsHeader{
+		Version: encryptedCredentialsVersion,
+		KDF:     key.kdf(),
+		Salt:    make([]byte, 16),
+	}
+
+	if header.KDF == kdfPBKDF2SHA256 {
+		header.Iterations = store.iterations
+	}
+
+	if _, err := rand.Read(header.Salt); err != nil {
+		return fmt.Errorf("Unable to generate salt: %s", err.Error())
+	}
+
+	file, err := encryptCredentials(key, header, plaintext)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(file, "", "\t")
+	if err != nil {
+		return fmt.Errorf("Unable to serialize credentials: %s", err.Error())
+	}
+
+	return writePrivateFile(store.path, content)
+}
+
+// encryptCredentials encrypts the given plaintext with the key derived
+// from the given key and header.
+func encryptCredentials(key CredentialKey, header encryptedCredentialsHeader, plaintext []byte) (encryptedCredentialsFile, error) {
+	aead, err := newCredentialsCipher(key, header)
+	if err != nil {
+		return encryptedCredentialsFile{}, err
+	}
+
+	header.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(header.Nonce); err != nil {
+		return encryptedCredentialsFile{}, fmt.Errorf("Unable to generate nonce: %s", err.Error())
+	}
+
+	ciphertext := aead.Seal(nil, header.Nonce, plaintext, header.additionalData())
+	return encryptedCredentialsFile{header, ciphertext}, nil
+}
+
+// decryptCredentials decrypts the given file and returns the plaintext.
+func decryptCredentials(key CredentialKey, file encryptedCredentialsFile) ([]byte, error) {
+	aead, err := newCredentialsCipher(key, file.encryptedCredentialsHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("Invalid nonce size %d: %w", len(file.Nonce), ErrUnsupportedCredentialsFormat)
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, file.additionalData())
+	if err != nil {
+		return nil, ErrInvalidCredentialKey
+	}
+
+	return plaintext, nil
+}
+
+// newCredentialsCipher returns the AES-256-GCM cipher for the given
+// key and header.
+func newCredentialsCipher(key CredentialKey, header encryptedCredentialsHeader) (cipher.AEAD, error) {
+	derivedKey, err := key.derive(header)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create cipher: %s", err.Error())
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// CopyCredentials reads the credentials from the given provider and
+// saves them with the given saver. It can be used to migrate
+// credentials from one store to another, e.g. from a plaintext
+// FileCredentialStore to an EncryptedCredentialStore.
+func CopyCredentials(from CredentialProvider, to CredentialSaver) error {
+	credentials, err := from.GetCredentials()
+	if err != nil {
+		return err
+	}
+
+	return to.SaveCredentials(credentials)
+}
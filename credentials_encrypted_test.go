@@ -0,0 +1,148 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// newTestEncryptedCredentialStore creates an encrypted store with a
+// low number of iterations so the tests run fast.
+func newTestEncryptedCredentialStore(t *testing.T, path string, key CredentialKey) *EncryptedCredentialStore {
+	store, err := NewEncryptedCredentialStore(path, key)
+	if err != nil {
+		t.Fatalf("NewEncryptedCredentialStore(%q) returned an error: %s", path, err.Error())
+	}
+
+	store.iterations = 1000
+	return store
+}
+
+// Saved credentials should be readable with the same passphrase and must not be stored in plaintext.
+func Test_EncryptedCredentialStore_PassphraseKey_SaveAndGetCredentials_CredentialsAreEncrypted(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	store := newTestEncryptedCredentialStore(t, path, PassphraseKey("correct horse battery staple"))
+	credentials := APICredentials{"john.doe@example.com", "ApItOken"}
+
+	// act
+	saveError := store.SaveCredentials(credentials)
+	result, getError := store.GetCredentials()
+
+	// assert
+	if saveError != nil || getError != nil || result != credentials {
+		t.Fail()
+		t.Logf("GetCredentials() should return %v but returned %v (save: %v, get: %v)", credentials, result, saveError, getError)
+	}
+
+	content, _ := ioutil.ReadFile(path)
+	if bytes.Contains(content, []byte("ApItOken")) {
+		t.Fail()
+		t.Logf("The credentials file must not contain the token in plaintext")
+	}
+}
+
+// GetCredentials should return ErrInvalidCredentialKey if the passphrase is wrong.
+func Test_EncryptedCredentialStore_WrongPassphrase_GetCredentials_ErrInvalidCredentialKeyIsReturned(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	newTestEncryptedCredentialStore(t, path, PassphraseKey("secret")).SaveCredentials(APICredentials{"john.doe@example.com", "ApItOken"})
+	store := newTestEncryptedCredentialStore(t, path, PassphraseKey("wrong"))
+
+	// act
+	_, err := store.GetCredentials()
+
+	// assert
+	if !errors.Is(err, ErrInvalidCredentialKey) {
+		t.Fail()
+		t.Logf("GetCredentials() should return ErrInvalidCredentialKey but returned %v", err)
+	}
+}
+
+// A store that uses a key file should be able to read its own credentials.
+func Test_EncryptedCredentialStore_KeyFileKey_SaveAndGetCredentials_CredentialsAreReturned(t *testing.T) {
+	// arrange
+	directory := t.TempDir()
+	keyFile := filepath.Join(directory, "key")
+	ioutil.WriteFile(keyFile, bytes.Repeat([]byte("k"), 32), 0600)
+	store := newTestEncryptedCredentialStore(t, filepath.Join(directory, "credentials.enc"), KeyFileKey(keyFile))
+	credentials := APICredentials{"john.doe@example.com", "ApItOken"}
+
+	// act
+	saveError := store.SaveCredentials(credentials)
+	result, getError := store.GetCredentials()
+
+	// assert
+	if saveError != nil || getError != nil || result != credentials {
+		t.Fail()
+		t.Logf("GetCredentials() should return %v but returned %v (save: %v, get: %v)", credentials, result, saveError, getError)
+	}
+}
+
+// Rekey should make the credentials readable with the new key only.
+func Test_EncryptedCredentialStore_Rekey_CredentialsAreOnlyReadableWithNewKey(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	store := newTestEncryptedCredentialStore(t, path, PassphraseKey("old"))
+	store.SaveCredentials(APICredentials{"john.doe@example.com", "ApItOken"})
+
+	// act
+	rekeyError := store.Rekey(PassphraseKey("new"))
+
+	// assert
+	if rekeyError != nil {
+		t.Fatalf("Rekey() returned an error: %s", rekeyError.Error())
+	}
+
+	if _, err := newTestEncryptedCredentialStore(t, path, PassphraseKey("new")).GetCredentials(); err != nil {
+		t.Fail()
+		t.Logf("The credentials should be readable with the new key: %s", err.Error())
+	}
+
+	if _, err := newTestEncryptedCredentialStore(t, path, PassphraseKey("old")).GetCredentials(); !errors.Is(err, ErrInvalidCredentialKey) {
+		t.Fail()
+		t.Logf("The credentials should no longer be readable with the old key but returned %v", err)
+	}
+}
+
+// GetCredentials should reject files with an unknown format version.
+func Test_EncryptedCredentialStore_UnknownVersion_GetCredentials_ErrUnsupportedCredentialsFormatIsReturned(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	ioutil.WriteFile(path, []byte(`{"version": 99, "kdf": "pbkdf2-sha256"}`), 0600)
+	store := newTestEncryptedCredentialStore(t, path, PassphraseKey("secret"))
+
+	// act
+	_, err := store.GetCredentials()
+
+	// assert
+	if !errors.Is(err, ErrUnsupportedCredentialsFormat) {
+		t.Fail()
+		t.Logf("GetCredentials() should return ErrUnsupportedCredentialsFormat but returned %v", err)
+	}
+}
+
+// CopyCredentials should migrate plaintext credentials into the encrypted store.
+func Test_CopyCredentials_FileStoreToEncryptedStore_CredentialsAreMigrated(t *testing.T) {
+	// arrange
+	directory := t.TempDir()
+	fileStore, _ := NewFileCredentialStore(filepath.Join(directory, "credentials.json"))
+	fileStore.SaveCredentials(APICredentials{"john.doe@example.com", "ApItOken"})
+	encryptedStore := newTestEncryptedCredentialStore(t, filepath.Join(directory, "credentials.enc"), PassphraseKey("secret"))
+
+	// act
+	err := CopyCredentials(fileStore, encryptedStore)
+
+	// assert
+	credentials, getError := encryptedStore.GetCredentials()
+	if err != nil || getError != nil || credentials.Token != "ApItOken" {
+		t.Fail()
+		t.Logf("CopyCredentials() should have migrated the credentials (copy: %v, get: %v)", err, getError)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DefaultEmailVariable is the name of the environment variable that
+// contains the DNSimple e-mail address by default.
+const DefaultEmailVariable = "DNSIMPLE_EMAIL"
+
+// DefaultTokenVariable is the name of the environment variable that
+// contains the DNSimple API token by default.
+const DefaultTokenVariable = "DNSIMPLE_TOKEN"
+
+// fileVariableSuffix is appended to the name of an environment variable
+// to get the name of the variable that points to a file containing the value.
+const fileVariableSuffix = "_FILE"
+
+// NewEnvironmentCredentialProvider creates a new CredentialProvider that
+// reads the e-mail address and the API token from the environment
+// variables with the given names. If a variable is not set, the provider
+// reads the value from the file named by the variable with the "_FILE"
+// suffix (e.g. DNSIMPLE_TOKEN_FILE). Empty names are replaced with
+// DefaultEmailVariable and DefaultTokenVariable.
+func NewEnvironmentCredentialProvider(emailVariable, tokenVariable string) *EnvironmentCredentialProvider {
+	if isEmpty(emailVariable) {
+		emailVariable = DefaultEmailVariable
+	}
+
+	if isEmpty(tokenVariable) {
+		tokenVariable = DefaultTokenVariable
+	}
+
+	return &EnvironmentCredentialProvider{emailVariable, tokenVariable, os.LookupEnv}
+}
+
+// EnvironmentCredentialProvider reads APICredentials from environment variables.
+type EnvironmentCredentialProvider struct {
+	emailVariable string
+	tokenVariable string
+
+	lookupEnv func(key string) (string, bool)
+}
+
+// GetCredentials returns the credentials from the environment.
+// Returns ErrNoCredentials if neither the e-mail nor the token variables are set.
+func (provider *EnvironmentCredentialProvider) GetCredentials() (APICredentials, error) {
+	email, emailError := provider.getValue(provider.emailVariable)
+	token, tokenError := provider.getValue(provider.tokenVariable)
+
+	// nothing configured at all
+	if errors.Is(emailError, ErrNoCredentials) && errors.Is(tokenError, ErrNoCredentials) {
+		return APICredentials{}, ErrNoCredentials
+	}
+
+	if emailError != nil {
+		return APICredentials{}, emailError
+	}
+
+	if tokenError != nil {
+		return APICredentials{}, tokenError
+	}
+
+	return NewAPICredentials(email, token)
+}
+
+// getValue returns the value of the environment variable with the given
+// name or the content of the file referenced by its "_FILE" variant.
+// Returns ErrNoCredentials if neither of the two is set.
+func (provider *EnvironmentCredentialProvider) getValue(name string) (string, error) {
+	value, valueIsSet := provider.lookupEnv(name)
+	path, pathIsSet := provider.lookupEnv(name + fileVariableSuffix)
+
+	if valueIsSet && pathIsSet {
+		return "", fmt.Errorf("Both %s and %s%s are set. Please use only one of them", name, name, fileVariableSuffix)
+	}
+
+	if valueIsSet {
+		return value, nil
+	}
+
+	if !pathIsSet {
+		return "", fmt.Errorf("%s is not set: %w", name, ErrNoCredentials)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s%s (%q): %s", name, fileVariableSuffix, path, err.Error())
+	}
+
+	// secrets files usually end with a newline
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// CredentialSource is a named CredentialProvider.
+type CredentialSource struct {
+	// Name identifies the source (e.g. "environment" or "file").
+	Name string
+
+	// Provider returns the credentials of this source.
+	Provider CredentialProvider
+}
+
+// NewChainCredentialProvider creates a new CredentialProvider that asks
+// the given sources for credentials in the given order.
+func NewChainCredentialProvider(sources ...CredentialSource) *ChainCredentialProvider {
+	return &ChainCredentialProvider{sources}
+}
+
+// ChainCredentialProvider returns the credentials of the first source
+// that has credentials stored.
+type ChainCredentialProvider struct {
+	sources []CredentialSource
+}
+
+// GetCredentials returns the credentials of the first source that
+// has credentials stored.
+func (chain *ChainCredentialProvider) GetCredentials() (APICredentials, error) {
+	credentials, _, err := chain.GetCredentialsWithSource()
+	return credentials, err
+}
+
+// GetCredentialsWithSource returns the credentials of the first source
+// that has credentials stored together with the name of that source.
+// Sources that return ErrNoCredentials are skipped. Any other error
+// stops the search and is returned to the caller.
+// Returns ErrNoCredentials if none of the sources has credentials stored.
+func (chain *ChainCredentialProvider) GetCredentialsWithSource() (APICredentials, string, error) {
+	for _, source := range chain.sources {
+		credentials, err := source.Provider.GetCredentials()
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		if err != nil {
+			return APICredentials{}, source.Name, fmt.Errorf("Unable to get credentials from %s: %w", source.Name, err)
+		}
+
+		return credentials, source.Name, nil
+	}
+
+	return APICredentials{}, "", ErrNoCredentials
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// testEnvironment returns a lookup function for the given variables.
+func testEnvironment(variables map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := variables[key]
+		return value, ok
+	}
+}
+
+// GetCredentials should return the credentials from the configured variables.
+func Test_EnvironmentCredentialProvider_VariablesAreSet_CredentialsAreReturned(t *testing.T) {
+	// arrange
+	provider := NewEnvironmentCredentialProvider("MY_EMAIL", "MY_TOKEN")
+	provider.lookupEnv = testEnvironment(map[string]string{
+		"MY_EMAIL": "john.doe@example.com",
+		"MY_TOKEN": "ApItOken",
+	})
+
+	// act
+	credentials, err := provider.GetCredentials()
+
+	// assert
+	if err != nil || credentials != (APICredentials{"john.doe@example.com", "ApItOken"}) {
+		t.Fail()
+		t.Logf("GetCredentials() returned %v, %v", credentials, err)
+	}
+}
+
+// GetCredentials should read the values from files if the _FILE variants are set.
+func Test_EnvironmentCredentialProvider_FileVariablesAreSet_CredentialsAreReadFromFiles(t *testing.T) {
+	// arrange
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	ioutil.WriteFile(tokenFile, []byte("ApItOken\n"), 0600)
+
+	provider := NewEnvironmentCredentialProvider("", "")
+	provider.lookupEnv = testEnvironment(map[string]string{
+		"DNSIMPLE_EMAIL":      "john.doe@example.com",
+		"DNSIMPLE_TOKEN_FILE": tokenFile,
+	})
+
+	// act
+	credentials, err := provider.GetCredentials()
+
+	// assert
+	if err != nil || credentials.Token != "ApItOken" {
+		t.Fail()
+		t.Logf("GetCredentials() should have read the token from %q but returned %v, %v", tokenFile, credentials, err)
+	}
+}
+
+// GetCredentials should return ErrNoCredentials if no variable is set.
+func Test_EnvironmentCredentialProvider_NoVariablesSet_ErrNoCredentialsIsReturned(t *testing.T) {
+	// arrange
+	provider := NewEnvironmentCredentialProvider("", "")
+	provider.lookupEnv = testEnvironment(nil)
+
+	// act
+	_, err := provider.GetCredentials()
+
+	// assert
+	if err != ErrNoCredentials {
+		t.Fail()
+		t.Logf("GetCredentials() should return ErrNoCredentials but returned %v", err)
+	}
+}
+
+// GetCredentials should return an error if only one of the variables is set
+// or if a variable and its _FILE variant are both set.
+func Test_EnvironmentCredentialProvider_IncompleteOrAmbiguousVariables_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []map[string]string{
+		{"DNSIMPLE_EMAIL": "john.doe@example.com"},
+		{"DNSIMPLE_TOKEN": "ApItOken"},
+		{"DNSIMPLE_EMAIL": "john.doe@example.com", "DNSIMPLE_TOKEN": "ApItOken", "DNSIMPLE_TOKEN_FILE": "/run/secrets/token"},
+	}
+
+	for _, input := range inputs {
+		provider := NewEnvironmentCredentialProvider("", "")
+		provider.lookupEnv = testEnvironment(input)
+
+		// act
+		_, err := provider.GetCredentials()
+
+		// assert
+		if err == nil || err == ErrNoCredentials {
+			t.Fail()
+			t.Logf("GetCredentials() should return a configuration error for %v but returned %v", input, err)
+		}
+	}
+}
+
+// The chain should skip sources without credentials and report the source that was used.
+func Test_ChainCredentialProvider_FirstSourceHasNoCredentials_SecondSourceIsUsed(t *testing.T) {
+	// arrange
+	chain := NewChainCredentialProvider(
+		CredentialSource{"environment", testCredentialsStore{getFunc: func() (APICredentials, error) {
+			return APICredentials{}, ErrNoCredentials
+		}}},
+		CredentialSource{"file", testCredentialsStore{getFunc: func() (APICredentials, error) {
+			return APICredentials{"john.doe@example.com", "ApItOken"}, nil
+		}}},
+	)
+
+	// act
+	credentials, source, err := chain.GetCredentialsWithSource()
+
+	// assert
+	if err != nil || source != "file" || credentials.Token != "ApItOken" {
+		t.Fail()
+		t.Logf("GetCredentialsWithSource() should have returned the credentials of the file source but returned %v, %q, %v", credentials, source, err)
+	}
+}
+
+// The chain should stop at the first source that fails.
+func Test_ChainCredentialProvider_SourceFails_ErrorIsReturned(t *testing.T) {
+	// arrange
+	chain := NewChainCredentialProvider(
+		CredentialSource{"file", testCredentialsStore{getFunc: func() (APICredentials, error) {
+			return APICredentials{}, fmt.Errorf("Unable to read file")
+		}}},
+		CredentialSource{"environment", testCredentialsStore{getFunc: func() (APICredentials, error) {
+			return APICredentials{"john.doe@example.com", "ApItOken"}, nil
+		}}},
+	)
+
+	// act
+	_, source, err := chain.GetCredentialsWithSource()
+
+	// assert
+	if err == nil || source != "file" {
+		t.Fail()
+		t.Logf("GetCredentialsWithSource() should have returned the error of the file source but returned %q, %v", source, err)
+	}
+}
+
+// The chain should return ErrNoCredentials if none of the sources has credentials.
+func Test_ChainCredentialProvider_NoSourceHasCredentials_ErrNoCredentialsIsReturned(t *testing.T) {
+	// arrange
+	chain := NewChainCredentialProvider()
+
+	// act
+	_, err := chain.GetCredentials()
+
+	// assert
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Fail()
+		t.Logf("GetCredentials() should return ErrNoCredentials but returned %v", err)
+	}
+}
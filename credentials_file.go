@@ -0,0 +1,216 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// credentialsFileName is the name of the file that is used by the
+// FileCredentialStore if no explicit path is given.
+const credentialsFileName = "credentials.json"
+
+// credentialsDirectoryName is the name of the dee-ns directory inside
+// the user's configuration directory.
+const credentialsDirectoryName = "dee-ns"
+
+// ErrNoCredentials is returned by credential providers if there are
+// no credentials stored.
+var ErrNoCredentials = errors.New("No credentials stored")
+
+// ErrPermissionsTooOpen is returned if a credentials file can be read
+// by users other than its owner.
+var ErrPermissionsTooOpen = errors.New("Credentials file permissions are too open")
+
+// PermissionsTooOpenError describes a credentials file that can be
+// accessed by users other than its owner.
+type PermissionsTooOpenError struct {
+	// Path is the path of the credentials file.
+	Path string
+
+	// Mode contains the permissions of the credentials file.
+	Mode os.FileMode
+}
+
+func (err *PermissionsTooOpenError) Error() string {
+	return fmt.Sprintf("The permissions %04o of %q are too open. The file must only be accessible by its owner (0600)", err.Mode.Perm(), err.Path)
+}
+
+// Is reports whether the given target is ErrPermissionsTooOpen.
+func (err *PermissionsTooOpenError) Is(target error) bool {
+	return target == ErrPermissionsTooOpen
+}
+
+// DefaultCredentialsFilePath returns the default location of the
+// credentials file. The file is located in $XDG_CONFIG_HOME/dee-ns or
+// in ~/.config/dee-ns if XDG_CONFIG_HOME is not set.
+func DefaultCredentialsFilePath() (string, error) {
+	configDirectory := os.Getenv("XDG_CONFIG_HOME")
+	if isEmpty(configDirectory) {
+		homeDirectory, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Unable to determine the home directory: %s", err.Error())
+		}
+
+		configDirectory = filepath.Join(homeDirectory, ".config")
+	}
+
+	return filepath.Join(configDirectory, credentialsDirectoryName, credentialsFileName), nil
+}
+
+// NewFileCredentialStore creates a new CredentialStore that keeps the
+// credentials in the file with the given path. If the path is empty
+// the DefaultCredentialsFilePath will be used.
+func NewFileCredentialStore(path string) (*FileCredentialStore, error) {
+	if isEmpty(path) {
+		defaultPath, err := DefaultCredentialsFilePath()
+		if err != nil {
+			return nil, err
+		}
+
+		path = defaultPath
+	}
+
+	return &FileCredentialStore{path}, nil
+}
+
+// FileCredentialStore reads and persists APICredentials in a JSON file
+// that is only accessible by the current user.
+type FileCredentialStore struct {
+	path string
+}
+
+// credentialsFile defines the JSON format of a credentials file.
+type credentialsFile struct {
+	Email string `json:"email"`
+	Token string `json:"token"`
+}
+
+// Path returns the path of the credentials file.
+func (store *FileCredentialStore) Path() string {
+	return store.path
+}
+
+// GetCredentials returns the credentials from the credentials file.
+// Returns ErrNoCredentials if the file does not exist and a
+// PermissionsTooOpenError if the file can be read by other users.
+func (store *FileCredentialStore) GetCredentials() (APICredentials, error) {
+	content, err := readPrivateFile(store.path)
+	if os.IsNotExist(err) {
+		return APICredentials{}, ErrNoCredentials
+	}
+
+	if err != nil {
+		return APICredentials{}, err
+	}
+
+	var file credentialsFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return APICredentials{}, fmt.Errorf("Unable to read credentials from %q: %s", store.path, err.Error())
+	}
+
+	return NewAPICredentials(file.Email, file.Token)
+}
+
+// SaveCredentials writes the given credentials to the credentials file.
+// The file is replaced atomically and is only readable by its owner.
+func (store *FileCredentialStore) SaveCredentials(credentials APICredentials) error {
+	if _, err := NewAPICredentials(credentials.Email, credentials.Token); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(credentialsFile{credentials.Email, credentials.Token}, "", "\t")
+	if err != nil {
+		return fmt.Errorf("Unable to serialize credentials: %s", err.Error())
+	}
+
+	return writePrivateFile(store.path, content)
+}
+
+// DeleteCredentials removes the credentials file. It is not an error
+// if the file does not exist.
+func (store *FileCredentialStore) DeleteCredentials() error {
+	if err := os.Remove(store.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to delete credentials file %q: %s", store.path, err.Error())
+	}
+
+	return nil
+}
+
+// readPrivateFile returns the content of the file with the given path.
+// Returns the os.Stat error if the file does not exist and a
+// PermissionsTooOpenError if users other than the owner have access to it.
+func readPrivateFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to access %q: %s", path, err.Error())
+	}
+
+	// file modes are not meaningful on Windows
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, &PermissionsTooOpenError{path, info.Mode()}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %q: %s", path, err.Error())
+	}
+
+	return content, nil
+}
+
+// writePrivateFile atomically replaces the file with the given path
+// with the given content. The file is created with 0600 permissions and
+// its parent directory with 0700 permissions.
+func writePrivateFile(path string, content []byte) error {
+	directory := filepath.Dir(path)
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return fmt.Errorf("Unable to create directory %q: %s", directory, err.Error())
+	}
+
+	temporaryFile, err := ioutil.TempFile(directory, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Unable to create temporary file in %q: %s", directory, err.Error())
+	}
+
+	// remove the temporary file if anything goes wrong
+	temporaryPath := temporaryFile.Name()
+	defer os.Remove(temporaryPath)
+
+	if err := temporaryFile.Chmod(0600); err != nil {
+		temporaryFile.Close()
+		return fmt.Errorf("Unable to set permissions of %q: %s", temporaryPath, err.Error())
+	}
+
+	if _, err := temporaryFile.Write(content); err != nil {
+		temporaryFile.Close()
+		return fmt.Errorf("Unable to write %q: %s", temporaryPath, err.Error())
+	}
+
+	if err := temporaryFile.Sync(); err != nil {
+		temporaryFile.Close()
+		return fmt.Errorf("Unable to write %q: %s", temporaryPath, err.Error())
+	}
+
+	if err := temporaryFile.Close(); err != nil {
+		return fmt.Errorf("Unable to write %q: %s", temporaryPath, err.Error())
+	}
+
+	if err := os.Rename(temporaryPath, path); err != nil {
+		return fmt.Errorf("Unable to replace %q: %s", path, err.Error())
+	}
+
+	return nil
+}
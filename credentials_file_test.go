@@ -0,0 +1,130 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// SaveCredentials followed by GetCredentials should return the saved credentials.
+func Test_FileCredentialStore_SaveCredentials_GetCredentialsReturnsSavedCredentials(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "dee-ns", "credentials.json")
+	store, _ := NewFileCredentialStore(path)
+	credentials := APICredentials{"john.doe@example.com", "ApItOken"}
+
+	// act
+	saveError := store.SaveCredentials(credentials)
+	result, getError := store.GetCredentials()
+
+	// assert
+	if saveError != nil || getError != nil {
+		t.Fail()
+		t.Logf("SaveCredentials() and GetCredentials() should not return an error. Save: %v, Get: %v", saveError, getError)
+	}
+
+	if result != credentials {
+		t.Fail()
+		t.Logf("GetCredentials() should have returned %v but returned %v", credentials, result)
+	}
+}
+
+// SaveCredentials should create the credentials file with 0600 permissions.
+func Test_FileCredentialStore_SaveCredentials_FileIsOnlyAccessibleByOwner(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	store, _ := NewFileCredentialStore(path)
+
+	// act
+	store.SaveCredentials(APICredentials{"john.doe@example.com", "ApItOken"})
+
+	// assert
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("SaveCredentials() should have created %q: %s", path, err.Error())
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Fail()
+		t.Logf("SaveCredentials() should have created the file with 0600 permissions but the permissions are %04o", info.Mode().Perm())
+	}
+}
+
+// GetCredentials should return ErrNoCredentials if the file does not exist.
+func Test_FileCredentialStore_FileDoesNotExist_GetCredentials_ErrNoCredentialsIsReturned(t *testing.T) {
+	// arrange
+	store, _ := NewFileCredentialStore(filepath.Join(t.TempDir(), "credentials.json"))
+
+	// act
+	_, err := store.GetCredentials()
+
+	// assert
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Fail()
+		t.Logf("GetCredentials() should return ErrNoCredentials if the file does not exist but returned %v", err)
+	}
+}
+
+// GetCredentials should refuse to read files that other users can read.
+func Test_FileCredentialStore_FileIsWorldReadable_GetCredentials_ErrPermissionsTooOpenIsReturned(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	ioutil.WriteFile(path, []byte(`{"email": "john.doe@example.com", "token": "ApItOken"}`), 0644)
+	os.Chmod(path, 0644)
+	store, _ := NewFileCredentialStore(path)
+
+	// act
+	_, err := store.GetCredentials()
+
+	// assert
+	var permissionsError *PermissionsTooOpenError
+	if !errors.Is(err, ErrPermissionsTooOpen) || !errors.As(err, &permissionsError) {
+		t.Fail()
+		t.Logf("GetCredentials() should return a PermissionsTooOpenError but returned %v", err)
+	}
+}
+
+// DeleteCredentials should remove the file and should not fail if there is no file.
+func Test_FileCredentialStore_DeleteCredentials_CredentialsAreGone(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	store, _ := NewFileCredentialStore(path)
+	store.SaveCredentials(APICredentials{"john.doe@example.com", "ApItOken"})
+
+	// act
+	firstError := store.DeleteCredentials()
+	secondError := store.DeleteCredentials()
+
+	// assert
+	if firstError != nil || secondError != nil {
+		t.Fail()
+		t.Logf("DeleteCredentials() should not return an error. First: %v, Second: %v", firstError, secondError)
+	}
+
+	if _, err := store.GetCredentials(); !errors.Is(err, ErrNoCredentials) {
+		t.Fail()
+		t.Logf("GetCredentials() should return ErrNoCredentials after the credentials were deleted but returned %v", err)
+	}
+}
+
+// The default path should be located in XDG_CONFIG_HOME if it is set.
+func Test_DefaultCredentialsFilePath_XDGConfigHomeIsSet_PathIsInXDGConfigHome(t *testing.T) {
+	// arrange
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/config")
+
+	// act
+	path, _ := DefaultCredentialsFilePath()
+
+	// assert
+	expected := filepath.Join("/tmp/config", "dee-ns", "credentials.json")
+	if path != expected {
+		t.Fail()
+		t.Logf("DefaultCredentialsFilePath() should return %q but returned %q", expected, path)
+	}
+}
@@ -72,3 +72,42 @@ func Test_newAPICredentials_InvalidValidEmailOrToken_ErrorIsReturned(t *testing.
 		}
 	}
 }
+
+func Test_NewDomainTokenCredentials_InvalidDomainOrToken_ErrorIsReturned(t *testing.T) {
+	// arrange
+	var inputs = []struct {
+		domain string
+		token  string
+	}{
+		{"example.com", ""},
+		{"", "DoMaInToKeN"},
+		{" ", " "},
+	}
+
+	// act
+	for _, input := range inputs {
+		_, err := NewDomainTokenCredentials(input.domain, input.token)
+
+		// assert
+		if err == nil {
+			t.Fail()
+			t.Logf("NewDomainTokenCredentials(%q, %q) should return an error because the given input is invalid.", input.domain, input.token)
+		}
+	}
+}
+
+func Test_NewAccessTokenCredentials_NoToken_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []string{"", " "}
+
+	// act
+	for _, input := range inputs {
+		_, err := NewAccessTokenCredentials("1010", input)
+
+		// assert
+		if err == nil {
+			t.Fail()
+			t.Logf("NewAccessTokenCredentials(%q, %q) should return an error because no token is given.", "1010", input)
+		}
+	}
+}
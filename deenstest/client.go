@@ -0,0 +1,472 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package deenstest provides fakes of the DNSimple API for testing code
+// that uses the deens package.
+package deenstest
+
+import (
+	"context"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Method identifies a method of the deens.DNSClient interface for error injection.
+type Method string
+
+const (
+	// MethodUpdateRecord identifies UpdateRecord.
+	MethodUpdateRecord Method = "UpdateRecord"
+
+	// MethodGetRecords identifies GetRecords.
+	MethodGetRecords Method = "GetRecords"
+
+	// MethodGetDomains identifies GetDomains.
+	MethodGetDomains Method = "GetDomains"
+
+	// MethodCreateRecord identifies CreateRecord.
+	MethodCreateRecord Method = "CreateRecord"
+
+	// MethodDestroyRecord identifies DestroyRecord.
+	MethodDestroyRecord Method = "DestroyRecord"
+)
+
+// NewFakeDNSClient creates an in-memory DNS client that contains the
+// given domains without any records.
+func NewFakeDNSClient(domains ...string) *FakeDNSClient {
+	client := &FakeDNSClient{
+		domains:      make(map[string]*fakeDomain),
+		errors:       make(map[Method]error),
+		failures:     make(map[Method][]error),
+		nextDomainID: 1,
+		nextRecordID: 1,
+	}
+
+	for _, domain := range domains {
+		client.AddDomain(domain)
+	}
+
+	return client
+}
+
+// FakeDNSClient is an in-memory implementation of the deens.DNSClient,
+// the deens.ContextDNSClient and the deens.PriorityRecordWriter
+// interface. It validates records the
+// way the DNSimple API does and returns *deens.APIError values with the
+// same status codes:
+//
+//   - unknown domains and records: 404
+//   - missing names, types or contents and invalid TTLs: 422
+//   - records with the same name, type, content and priority: 422
+//   - CNAME records that share their name with other records: 422
+//
+// It is safe for concurrent use.
+type FakeDNSClient struct {
+	lock sync.Mutex
+
+	domains      map[string]*fakeDomain
+	nextDomainID int
+	nextRecordID int64
+
+	errors   map[Method]error
+	failures map[Method][]error
+}
+
+// fakeDomain contains a domain and its records.
+type fakeDomain struct {
+	domain  dnsimple.Domain
+	records []dnsimple.Record
+}
+
+// AddDomain adds an empty domain with the given name and returns it.
+// Existing domains are returned unchanged.
+func (client *FakeDNSClient) AddDomain(name string) dnsimple.Domain {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	key := normalizeName(name)
+	if existing, ok := client.domains[key]; ok {
+		return existing.domain
+	}
+
+	domain := dnsimple.Domain{Id: client.nextDomainID, Name: key, UnicodeName: key, State: "hosted"}
+	client.nextDomainID++
+	client.domains[key] = &fakeDomain{domain: domain}
+	return domain
+}
+
+// AddRecord adds the given record to the given domain and returns it
+// with its new ID. The same validation rules as for CreateRecord apply.
+func (client *FakeDNSClient) AddRecord(domain string, record dnsimple.Record) (dnsimple.Record, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	return client.addRecord(domain, record)
+}
+
+// Records returns a copy of the records of the given domain ordered by ID.
+func (client *FakeDNSClient) Records(domain string) []dnsimple.Record {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	fake, ok := client.domains[normalizeName(domain)]
+	if !ok {
+		return nil
+	}
+
+	return append([]dnsimple.Record(nil), fake.records...)
+}
+
+// SetError makes every call of the given method fail with the given
+// error until SetError is called with a nil error.
+func (client *FakeDNSClient) SetError(method Method, err error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err == nil {
+		delete(client.errors, method)
+		return
+	}
+
+	client.errors[method] = err
+}
+
+// FailNext makes the next call of the given method fail with the given
+// error. Several failures are returned in the order they were added.
+// The call does not change any records.
+func (client *FakeDNSClient) FailNext(method Method, err error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.failures[method] = append(client.failures[method], err)
+}
+
+// UpdateRecord update the DNS record with the given id.
+func (client *FakeDNSClient) UpdateRecord(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.UpdateRecordContext(context.Background(), domain, id, opts)
+}
+
+// GetRecords returns all DNS records for the given domain.
+func (client *FakeDNSClient) GetRecords(domain string) ([]dnsimple.Record, error) {
+	return client.GetRecordsContext(context.Background(), domain)
+}
+
+// GetDomains returns a list of domain.
+func (client *FakeDNSClient) GetDomains() ([]dnsimple.Domain, error) {
+	return client.GetDomainsContext(context.Background())
+}
+
+// CreateRecord creates a new DNS record for the given domain.
+func (client *FakeDNSClient) CreateRecord(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.CreateRecordContext(context.Background(), domain, opts)
+}
+
+// DestroyRecord deletes the DNS record with the given id.
+func (client *FakeDNSClient) DestroyRecord(domain string, id string) error {
+	return client.DestroyRecordContext(context.Background(), domain, id)
+}
+
+// UpdateRecordContext update the DNS record with the given id.
+func (client *FakeDNSClient) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.updateRecord(ctx, domain, id, opts, nil)
+}
+
+// UpdatePriorityRecordContext updates the DNS record with the given id
+// and sets its priority.
+func (client *FakeDNSClient) UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.updateRecord(ctx, domain, id, opts, &priority)
+}
+
+// updateRecord updates the DNS record with the given id. The priority
+// is only changed if it is not nil.
+func (client *FakeDNSClient) updateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority *int) (string, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err := client.injectedError(ctx, MethodUpdateRecord); err != nil {
+		return "", err
+	}
+
+	fake, index, err := client.findRecord(domain, id)
+	if err != nil {
+		return "", fmt.Errorf("Error updating record: %w", err)
+	}
+
+	record := fake.records[index]
+	if opts.Name != "" {
+		record.Name = opts.Name
+	}
+
+	if opts.Type != "" {
+		record.RecordType = opts.Type
+	}
+
+	if opts.Value != "" {
+		record.Content = opts.Value
+	}
+
+	if opts.Ttl != "" {
+		ttl, err := parseTTL(domain, opts.Ttl)
+		if err != nil {
+			return "", fmt.Errorf("Error updating record: %w", err)
+		}
+
+		record.Ttl = ttl
+	}
+
+	if priority != nil {
+		record.Prio = int64(*priority)
+	}
+
+	if err := validateRecord(fake, record); err != nil {
+		return "", fmt.Errorf("Error updating record: %w", err)
+	}
+
+	fake.records[index] = record
+	return id, nil
+}
+
+// GetRecordsContext returns all DNS records for the given domain.
+func (client *FakeDNSClient) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err := client.injectedError(ctx, MethodGetRecords); err != nil {
+		return nil, err
+	}
+
+	fake, err := client.findDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]dnsimple.Record{}, fake.records...), nil
+}
+
+// GetDomainsContext returns a list of domain ordered by ID.
+func (client *FakeDNSClient) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err := client.injectedError(ctx, MethodGetDomains); err != nil {
+		return nil, err
+	}
+
+	domains := []dnsimple.Domain{}
+	for _, fake := range client.domains {
+		domains = append(domains, fake.domain)
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Id < domains[j].Id
+	})
+
+	return domains, nil
+}
+
+// CreateRecordContext creates a new DNS record for the given domain.
+func (client *FakeDNSClient) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.createRecord(ctx, domain, opts, nil)
+}
+
+// CreatePriorityRecordContext creates a new DNS record with the given
+// priority for the given domain.
+func (client *FakeDNSClient) CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.createRecord(ctx, domain, opts, &priority)
+}
+
+// createRecord creates a new DNS record for the given domain. The
+// priority is only set if it is not nil.
+func (client *FakeDNSClient) createRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority *int) (string, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err := client.injectedError(ctx, MethodCreateRecord); err != nil {
+		return "", err
+	}
+
+	record := dnsimple.Record{Name: opts.Name, RecordType: opts.Type, Content: opts.Value, Ttl: 3600}
+	if opts.Ttl != "" {
+		ttl, err := parseTTL(domain, opts.Ttl)
+		if err != nil {
+			return "", fmt.Errorf("Error creating record: %w", err)
+		}
+
+		record.Ttl = ttl
+	}
+
+	if priority != nil {
+		record.Prio = int64(*priority)
+	}
+
+	created, err := client.addRecord(domain, record)
+	if err != nil {
+		return "", fmt.Errorf("Error creating record: %w", err)
+	}
+
+	return created.StringId(), nil
+}
+
+// DestroyRecordContext deletes the DNS record with the given id.
+func (client *FakeDNSClient) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err := client.injectedError(ctx, MethodDestroyRecord); err != nil {
+		return err
+	}
+
+	fake, index, err := client.findRecord(domain, id)
+	if err != nil {
+		return fmt.Errorf("Error destroying record: %w", err)
+	}
+
+	fake.records = append(fake.records[:index], fake.records[index+1:]...)
+	return nil
+}
+
+// injectedError returns the context error or the error that was
+// injected for the given method (if any). The lock must be held.
+func (client *FakeDNSClient) injectedError(ctx context.Context, method Method) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if failures := client.failures[method]; len(failures) > 0 {
+		client.failures[method] = failures[1:]
+		return failures[0]
+	}
+
+	return client.errors[method]
+}
+
+// addRecord validates the given record and adds it to the given
+// domain. The lock must be held.
+func (client *FakeDNSClient) addRecord(domain string, record dnsimple.Record) (dnsimple.Record, error) {
+	fake, err := client.findDomain(domain)
+	if err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	record.Id = 0
+	record.DomainId = int64(fake.domain.Id)
+	if err := validateRecord(fake, record); err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	record.Id = client.nextRecordID
+	client.nextRecordID++
+	fake.records = append(fake.records, record)
+	return record, nil
+}
+
+// findDomain returns the given domain or a 404 error. The lock must be held.
+func (client *FakeDNSClient) findDomain(domain string) (*fakeDomain, error) {
+	fake, ok := client.domains[normalizeName(domain)]
+	if !ok {
+		return nil, newAPIError(http.StatusNotFound, domain, "", fmt.Sprintf("Domain `%s` not found", domain), nil)
+	}
+
+	return fake, nil
+}
+
+// findRecord returns the domain and the index of the record with the
+// given ID or a 404 error. The lock must be held.
+func (client *FakeDNSClient) findRecord(domain, id string) (*fakeDomain, int, error) {
+	fake, err := client.findDomain(domain)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for index, record := range fake.records {
+		if record.StringId() == id {
+			return fake, index, nil
+		}
+	}
+
+	return nil, 0, newAPIError(http.StatusNotFound, domain, id, fmt.Sprintf("Record `%s` not found", id), nil)
+}
+
+// validateRecord checks the given record against the rules of the
+// DNSimple API and the other records of the given domain.
+func validateRecord(fake *fakeDomain, record dnsimple.Record) error {
+	domain := fake.domain.Name
+	recordID := ""
+	if record.Id != 0 {
+		recordID = record.StringId()
+	}
+
+	validationErrors := make(map[string][]string)
+	if strings.TrimSpace(record.RecordType) == "" {
+		validationErrors["record_type"] = append(validationErrors["record_type"], "can't be blank")
+	}
+
+	if strings.TrimSpace(record.Content) == "" {
+		validationErrors["content"] = append(validationErrors["content"], "can't be blank")
+	}
+
+	if record.RecordType == "CNAME" && record.Name == "" {
+		validationErrors["name"] = append(validationErrors["name"], "CNAME records cannot be added to the domain apex")
+	}
+
+	if len(validationErrors) > 0 {
+		return newAPIError(http.StatusUnprocessableEntity, domain, recordID, "Validation failed", validationErrors)
+	}
+
+	for _, existing := range fake.records {
+		if existing.Id == record.Id || existing.Name != record.Name {
+			continue
+		}
+
+		if existing.RecordType == record.RecordType && existing.Content == record.Content && existing.Prio == record.Prio {
+			return newAPIError(http.StatusUnprocessableEntity, domain, recordID, "Validation failed", map[string][]string{
+				"base": {"Zone record already exists"},
+			})
+		}
+
+		if existing.RecordType == "CNAME" || record.RecordType == "CNAME" {
+			return newAPIError(http.StatusUnprocessableEntity, domain, recordID, "Validation failed", map[string][]string{
+				"base": {"A CNAME record cannot coexist with other records with the same name"},
+			})
+		}
+	}
+
+	return nil
+}
+
+// parseTTL parses the given TTL or returns a 422 error.
+func parseTTL(domain, value string) (int64, error) {
+	ttl, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ttl < 1 {
+		return 0, newAPIError(http.StatusUnprocessableEntity, domain, "", "Validation failed", map[string][]string{
+			"ttl": {"is not a number"},
+		})
+	}
+
+	return ttl, nil
+}
+
+// newAPIError creates an API error with the given status code.
+func newAPIError(statusCode int, domain, recordID, message string, validationErrors map[string][]string) *deens.APIError {
+	return &deens.APIError{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Message:    message,
+		Errors:     validationErrors,
+		Domain:     domain,
+		RecordID:   recordID,
+	}
+}
+
+// normalizeName returns the given domain name in lower case without a
+// trailing dot.
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
@@ -0,0 +1,217 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deenstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"testing"
+)
+
+// CreateRecord should assign increasing IDs.
+func Test_FakeDNSClient_CreateRecord_IDsAreAssigned(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+
+	// act
+	firstID, firstError := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"})
+	secondID, secondError := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "AAAA", Value: "::1", Ttl: "600"})
+
+	// assert
+	if firstError != nil || secondError != nil || firstID != "1" || secondID != "2" {
+		t.Fatalf("CreateRecord() should return the IDs 1 and 2 but returned %q, %q (%v, %v)", firstID, secondID, firstError, secondError)
+	}
+
+	records := client.Records("example.com")
+	if len(records) != 2 || records[0].Ttl != 3600 || records[1].Ttl != 600 {
+		t.Fail()
+		t.Logf("The records should have been stored with their TTLs but the domain contains %v", records)
+	}
+}
+
+// Records that violate the rules of DNSimple should be rejected with a validation error.
+func Test_FakeDNSClient_CreateRecord_InvalidRecord_ValidationErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []*dnsimple.ChangeRecord{
+		{Name: "www", Type: "A", Value: "127.0.0.1"},
+		{Name: "www", Type: "CNAME", Value: "example.org"},
+		{Name: "", Type: "CNAME", Value: "example.org"},
+		{Name: "mail", Type: "", Value: "127.0.0.1"},
+		{Name: "mail", Type: "A", Value: ""},
+		{Name: "mail", Type: "A", Value: "127.0.0.1", Ttl: "soon"},
+	}
+
+	for _, input := range inputs {
+		client := NewFakeDNSClient("example.com")
+		client.AddRecord("example.com", dnsimple.Record{Name: "www", RecordType: "A", Content: "127.0.0.1"})
+
+		// act
+		_, err := client.CreateRecord("example.com", input)
+
+		// assert
+		var apiError *deens.APIError
+		if !errors.Is(err, deens.ErrValidation) || !errors.As(err, &apiError) || apiError.StatusCode != 422 {
+			t.Fail()
+			t.Logf("CreateRecord(%+v) should return a 422 error but returned %v", input, err)
+		}
+	}
+}
+
+// Operations on unknown domains and records should fail with a not found error.
+func Test_FakeDNSClient_UnknownDomainOrRecord_NotFoundErrorIsReturned(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+
+	// act
+	_, recordsError := client.GetRecords("example.org")
+	_, updateError := client.UpdateRecord("example.com", "42", &dnsimple.ChangeRecord{Value: "127.0.0.1"})
+	destroyError := client.DestroyRecord("example.com", "42")
+
+	// assert
+	for _, err := range []error{recordsError, updateError, destroyError} {
+		if !errors.Is(err, deens.ErrNotFound) {
+			t.Fail()
+			t.Logf("A not found error should have been returned but got %v", err)
+		}
+	}
+}
+
+// UpdateRecord must not create duplicates of other records.
+func Test_FakeDNSClient_UpdateRecord_Duplicate_ValidationErrorIsReturned(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+	client.AddRecord("example.com", dnsimple.Record{Name: "www", RecordType: "A", Content: "127.0.0.1"})
+	second, _ := client.AddRecord("example.com", dnsimple.Record{Name: "www", RecordType: "A", Content: "127.0.0.2"})
+
+	// act
+	_, err := client.UpdateRecord("example.com", second.StringId(), &dnsimple.ChangeRecord{Value: "127.0.0.1"})
+
+	// assert
+	if !errors.Is(err, deens.ErrValidation) || client.Records("example.com")[1].Content != "127.0.0.2" {
+		t.Fail()
+		t.Logf("UpdateRecord() should reject the duplicate and keep the record unchanged but returned %v", err)
+	}
+}
+
+// Injected errors should be returned without changing any records.
+func Test_FakeDNSClient_ErrorInjection_ErrorsAreReturned(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+	injected := fmt.Errorf("Injected error")
+	client.FailNext(MethodCreateRecord, injected)
+	client.SetError(MethodGetDomains, injected)
+
+	// act
+	_, firstError := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"})
+	_, secondError := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"})
+	_, domainsError := client.GetDomains()
+	client.SetError(MethodGetDomains, nil)
+	_, clearedError := client.GetDomains()
+
+	// assert
+	if firstError != injected || secondError != nil || domainsError != injected || clearedError != nil {
+		t.Fail()
+		t.Logf("The injected errors were not returned as expected: %v, %v, %v, %v", firstError, secondError, domainsError, clearedError)
+	}
+
+	if len(client.Records("example.com")) != 1 {
+		t.Fail()
+		t.Logf("The failed call must not create a record")
+	}
+}
+
+// The fake should report cancelled contexts.
+func Test_FakeDNSClient_ContextCancelled_ContextErrorIsReturned(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// act
+	_, err := client.GetRecordsContext(ctx, "example.com")
+
+	// assert
+	if !errors.Is(err, context.Canceled) {
+		t.Fail()
+		t.Logf("GetRecordsContext() should return context.Canceled but returned %v", err)
+	}
+}
+
+// The DNSEditor should work with the fake client.
+func Test_FakeDNSClient_DNSEditor_RecordIsUpdated(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+	client.AddRecord("example.com", dnsimple.Record{Name: "www", RecordType: "A", Content: "127.0.0.1", Ttl: 600})
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client))
+
+	// act
+	err := editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.2"))
+
+	// assert
+	if records := client.Records("example.com"); err != nil || records[0].Content != "127.0.0.2" || records[0].Ttl != 600 {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should update the record but returned %v (records: %v)", err, records)
+	}
+}
+
+// The DNSEditor should create missing records and reject existing ones.
+func Test_FakeDNSClient_DNSEditor_CreateSubdomain(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client))
+
+	// act
+	id, createError := editor.CreateSubdomain("example.com", "www", 600, net.ParseIP("127.0.0.1"))
+	_, duplicateError := editor.CreateSubdomain("example.com", "www", 600, net.ParseIP("127.0.0.2"))
+
+	// assert
+	if records := client.Records("example.com"); createError != nil || len(records) != 1 || records[0].StringId() != id || records[0].Ttl != 600 {
+		t.Fail()
+		t.Logf("CreateSubdomain() should create the record but returned %q, %v (records: %v)", id, createError, records)
+	}
+
+	if !errors.Is(duplicateError, deens.ErrAlreadyExists) {
+		t.Fail()
+		t.Logf("CreateSubdomain() should return deens.ErrAlreadyExists for an existing record but returned %v", duplicateError)
+	}
+}
+
+// The ACME challenge solver should present an apex and a wildcard challenge at the same time.
+func Test_FakeDNSClient_ACMEChallengeSolver_ChallengesArePresentedAndRemoved(t *testing.T) {
+	// arrange
+	client := NewFakeDNSClient("example.com")
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client)).(deens.DNSTypedRecordEditor)
+	solver := deens.NewACMEChallengeSolver(editor, nil, deens.DefaultACMEPolicy())
+
+	// act
+	apexError := solver.Present("example.com", "", "apex-token")
+	wildcardError := solver.Present("example.com", "*", "wildcard-token")
+	recordsAfterPresent := client.Records("example.com")
+
+	solver.CleanUp("example.com", "", "apex-token")
+	solver.CleanUp("example.com", "*", "wildcard-token")
+
+	// assert
+	if apexError != nil || wildcardError != nil || len(recordsAfterPresent) != 2 {
+		t.Fail()
+		t.Logf("Present() should create two challenge records but returned %v, %v (records: %v)", apexError, wildcardError, recordsAfterPresent)
+	}
+
+	for _, record := range recordsAfterPresent {
+		if record.Name != "_acme-challenge" || record.RecordType != "TXT" || record.Ttl != 60 {
+			t.Fail()
+			t.Logf("Present() created an unexpected record: %+v", record)
+		}
+	}
+
+	if records := client.Records("example.com"); len(records) != 0 {
+		t.Fail()
+		t.Logf("CleanUp() should remove the challenge records but left %v", records)
+	}
+}
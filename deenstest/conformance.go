@@ -0,0 +1,323 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deenstest
+
+import (
+	"context"
+	"errors"
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"testing"
+)
+
+// ConformanceDomain is the domain that is used by the conformance tests.
+const ConformanceDomain = "example.com"
+
+// unknownRecordID is an ID that no record of the conformance tests has.
+const unknownRecordID = "987654321"
+
+// DNSClientFactory creates the DNS client under test. The client must
+// manage the given domain, which must not contain any records. Use
+// t.Cleanup to release the resources of the client.
+type DNSClientFactory func(t *testing.T, domain string) deens.DNSClient
+
+// DNSInfoProviderFactory creates the info provider under test. The
+// provider must return the given records (with IDs assigned) for the
+// given domain and must not know any other domain. Use t.Cleanup to
+// release the resources of the provider.
+type DNSInfoProviderFactory func(t *testing.T, domain string, records []dnsimple.Record) deens.DNSInfoProvider
+
+// RunDNSClientTests checks that the DNS clients created by the given
+// factory behave like the DNSimple API: records can be created, read,
+// updated and deleted; unknown domains and records are reported with
+// deens.ErrNotFound; duplicate and invalid records are rejected with
+// deens.ErrValidation; cancelled contexts are respected.
+func RunDNSClientTests(t *testing.T, factory DNSClientFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, client deens.DNSClient)
+	}{
+		{"GetDomains_DomainIsReturned", testGetDomainsDomainIsReturned},
+		{"CreateRecord_RecordIsStored", testCreateRecordRecordIsStored},
+		{"CreateRecord_Apex_RecordIsStored", testCreateRecordApexRecordIsStored},
+		{"UpdateRecord_ContentIsChanged", testUpdateRecordContentIsChanged},
+		{"DestroyRecord_RecordIsRemoved", testDestroyRecordRecordIsRemoved},
+		{"GetRecords_UnknownDomain_NotFoundErrorIsReturned", testGetRecordsUnknownDomainNotFoundErrorIsReturned},
+		{"UpdateRecord_UnknownRecord_NotFoundErrorIsReturned", testUpdateRecordUnknownRecordNotFoundErrorIsReturned},
+		{"DestroyRecord_UnknownRecord_NotFoundErrorIsReturned", testDestroyRecordUnknownRecordNotFoundErrorIsReturned},
+		{"CreateRecord_Duplicate_ValidationErrorIsReturned", testCreateRecordDuplicateValidationErrorIsReturned},
+		{"CreateRecord_InvalidRecord_ValidationErrorIsReturned", testCreateRecordInvalidRecordValidationErrorIsReturned},
+		{"ContextCancelled_ContextErrorIsReturned", testClientContextCancelledContextErrorIsReturned},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, factory(t, ConformanceDomain))
+		})
+	}
+}
+
+// conformanceRecords are the records of the info provider conformance tests.
+var conformanceRecords = []dnsimple.Record{
+	{Name: "", RecordType: "A", Content: "127.0.0.1", Ttl: 3600},
+	{Name: "www", RecordType: "A", Content: "127.0.0.2", Ttl: 600},
+	{Name: "www", RecordType: "AAAA", Content: "::2", Ttl: 600},
+	{Name: "mail", RecordType: "MX", Content: "mx.example.com", Ttl: 3600, Prio: 10},
+}
+
+// RunDNSInfoProviderTests checks that the info providers created by the
+// given factory return the records of a domain and its subdomains, report
+// missing records and unknown domains with deens.ErrNotFound and respect
+// cancelled contexts.
+func RunDNSInfoProviderTests(t *testing.T, factory DNSInfoProviderFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, infoProvider deens.DNSInfoProvider)
+	}{
+		{"GetDomainNames_DomainIsReturned", testGetDomainNamesDomainIsReturned},
+		{"GetDomainRecords_AllRecordsAreReturned", testGetDomainRecordsAllRecordsAreReturned},
+		{"GetSubdomainRecords_SubdomainRecordsAreReturned", testGetSubdomainRecordsSubdomainRecordsAreReturned},
+		{"GetSubdomainRecord_MatchingRecordIsReturned", testGetSubdomainRecordMatchingRecordIsReturned},
+		{"GetSubdomainRecord_Apex_ApexRecordIsReturned", testGetSubdomainRecordApexApexRecordIsReturned},
+		{"GetSubdomainRecord_MissingType_NotFoundErrorIsReturned", testGetSubdomainRecordMissingTypeNotFoundErrorIsReturned},
+		{"GetDomainRecords_UnknownDomain_NotFoundErrorIsReturned", testGetDomainRecordsUnknownDomainNotFoundErrorIsReturned},
+		{"ContextCancelled_ContextErrorIsReturned", testInfoProviderContextCancelledContextErrorIsReturned},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			records := append([]dnsimple.Record(nil), conformanceRecords...)
+			test.test(t, factory(t, ConformanceDomain, records))
+		})
+	}
+}
+
+func testGetDomainsDomainIsReturned(t *testing.T, client deens.DNSClient) {
+	domains, err := client.GetDomains()
+	if err != nil {
+		t.Fatalf("GetDomains() returned an error: %s", err.Error())
+	}
+
+	for _, domain := range domains {
+		if domain.Name == ConformanceDomain {
+			return
+		}
+	}
+
+	t.Fail()
+	t.Logf("GetDomains() should return %q but returned %v", ConformanceDomain, domains)
+}
+
+func testCreateRecordRecordIsStored(t *testing.T, client deens.DNSClient) {
+	id, err := client.CreateRecord(ConformanceDomain, &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1", Ttl: "600"})
+	if err != nil || id == "" {
+		t.Fatalf("CreateRecord() should return the ID of the new record but returned %q, %v", id, err)
+	}
+
+	record := requireRecord(t, client, id)
+	if record.Name != "www" || record.RecordType != "A" || record.Content != "127.0.0.1" || record.Ttl != 600 {
+		t.Fail()
+		t.Logf("The created record should match the given values but is %+v", record)
+	}
+}
+
+func testCreateRecordApexRecordIsStored(t *testing.T, client deens.DNSClient) {
+	id, err := client.CreateRecord(ConformanceDomain, &dnsimple.ChangeRecord{Name: "", Type: "TXT", Value: "v=spf1 -all"})
+	if err != nil {
+		t.Fatalf("CreateRecord() returned an error: %s", err.Error())
+	}
+
+	if record := requireRecord(t, client, id); record.Name != "" || record.Content != "v=spf1 -all" {
+		t.Fail()
+		t.Logf("The created record should be an apex record but is %+v", record)
+	}
+}
+
+func testUpdateRecordContentIsChanged(t *testing.T, client deens.DNSClient) {
+	id := requireCreateRecord(t, client, &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1", Ttl: "600"})
+
+	updatedID, err := client.UpdateRecord(ConformanceDomain, id, &dnsimple.ChangeRecord{Name: "www", Value: "127.0.0.2", Ttl: "600"})
+	if err != nil || updatedID != id {
+		t.Fatalf("UpdateRecord() should return the ID %q but returned %q, %v", id, updatedID, err)
+	}
+
+	record := requireRecord(t, client, id)
+	if record.Name != "www" || record.RecordType != "A" || record.Content != "127.0.0.2" {
+		t.Fail()
+		t.Logf("The record should have the new content but is %+v", record)
+	}
+
+	if records, _ := client.GetRecords(ConformanceDomain); len(records) != 1 {
+		t.Fail()
+		t.Logf("UpdateRecord() must not create a new record but the domain contains %v", records)
+	}
+}
+
+func testDestroyRecordRecordIsRemoved(t *testing.T, client deens.DNSClient) {
+	id := requireCreateRecord(t, client, &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"})
+
+	if err := client.DestroyRecord(ConformanceDomain, id); err != nil {
+		t.Fatalf("DestroyRecord() returned an error: %s", err.Error())
+	}
+
+	if records, err := client.GetRecords(ConformanceDomain); err != nil || len(records) != 0 {
+		t.Fail()
+		t.Logf("The domain should not contain any records but GetRecords() returned %v, %v", records, err)
+	}
+}
+
+func testGetRecordsUnknownDomainNotFoundErrorIsReturned(t *testing.T, client deens.DNSClient) {
+	if _, err := client.GetRecords("unknown-" + ConformanceDomain); !errors.Is(err, deens.ErrNotFound) {
+		t.Fail()
+		t.Logf("GetRecords() should return deens.ErrNotFound for an unknown domain but returned %v", err)
+	}
+}
+
+func testUpdateRecordUnknownRecordNotFoundErrorIsReturned(t *testing.T, client deens.DNSClient) {
+	if _, err := client.UpdateRecord(ConformanceDomain, unknownRecordID, &dnsimple.ChangeRecord{Value: "127.0.0.1"}); !errors.Is(err, deens.ErrNotFound) {
+		t.Fail()
+		t.Logf("UpdateRecord() should return deens.ErrNotFound for an unknown record but returned %v", err)
+	}
+}
+
+func testDestroyRecordUnknownRecordNotFoundErrorIsReturned(t *testing.T, client deens.DNSClient) {
+	if err := client.DestroyRecord(ConformanceDomain, unknownRecordID); !errors.Is(err, deens.ErrNotFound) {
+		t.Fail()
+		t.Logf("DestroyRecord() should return deens.ErrNotFound for an unknown record but returned %v", err)
+	}
+}
+
+func testCreateRecordDuplicateValidationErrorIsReturned(t *testing.T, client deens.DNSClient) {
+	changeRecord := &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"}
+	requireCreateRecord(t, client, changeRecord)
+
+	if _, err := client.CreateRecord(ConformanceDomain, changeRecord); !errors.Is(err, deens.ErrValidation) {
+		t.Fail()
+		t.Logf("CreateRecord() should return deens.ErrValidation for a duplicate record but returned %v", err)
+	}
+
+	if records, _ := client.GetRecords(ConformanceDomain); len(records) != 1 {
+		t.Fail()
+		t.Logf("The duplicate must not be stored but the domain contains %v", records)
+	}
+}
+
+func testCreateRecordInvalidRecordValidationErrorIsReturned(t *testing.T, client deens.DNSClient) {
+	if _, err := client.CreateRecord(ConformanceDomain, &dnsimple.ChangeRecord{Name: "www", Type: "", Value: "127.0.0.1"}); !errors.Is(err, deens.ErrValidation) {
+		t.Fail()
+		t.Logf("CreateRecord() should return deens.ErrValidation for a record without type but returned %v", err)
+	}
+}
+
+func testClientContextCancelledContextErrorIsReturned(t *testing.T, client deens.DNSClient) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := deens.AsContextDNSClient(client).GetRecordsContext(ctx, ConformanceDomain); !errors.Is(err, context.Canceled) {
+		t.Fail()
+		t.Logf("GetRecordsContext() should return context.Canceled but returned %v", err)
+	}
+}
+
+func testGetDomainNamesDomainIsReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	names, err := infoProvider.GetDomainNames()
+	if err != nil || len(names) != 1 || names[0] != ConformanceDomain {
+		t.Fail()
+		t.Logf("GetDomainNames() should return %q but returned %v, %v", ConformanceDomain, names, err)
+	}
+}
+
+func testGetDomainRecordsAllRecordsAreReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	records, err := infoProvider.GetDomainRecords(ConformanceDomain)
+	if err != nil || len(records) != len(conformanceRecords) {
+		t.Fail()
+		t.Logf("GetDomainRecords() should return %d records but returned %v, %v", len(conformanceRecords), records, err)
+	}
+}
+
+func testGetSubdomainRecordsSubdomainRecordsAreReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	records, err := infoProvider.GetSubdomainRecords(ConformanceDomain, "www")
+	if err != nil || len(records) != 2 {
+		t.Fatalf("GetSubdomainRecords() should return the 2 www records but returned %v, %v", records, err)
+	}
+
+	for _, record := range records {
+		if record.Name != "www" {
+			t.Fail()
+			t.Logf("GetSubdomainRecords() returned the record %+v of another subdomain", record)
+		}
+	}
+}
+
+func testGetSubdomainRecordMatchingRecordIsReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	record, err := infoProvider.GetSubdomainRecord(ConformanceDomain, "www", "AAAA")
+	if err != nil || record.Name != "www" || record.RecordType != "AAAA" || record.Content != "::2" || record.Id == 0 {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return the www AAAA record but returned %+v, %v", record, err)
+	}
+}
+
+func testGetSubdomainRecordApexApexRecordIsReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	record, err := infoProvider.GetSubdomainRecord(ConformanceDomain, "", "A")
+	if err != nil || record.Name != "" || record.Content != "127.0.0.1" {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return the apex A record but returned %+v, %v", record, err)
+	}
+}
+
+func testGetSubdomainRecordMissingTypeNotFoundErrorIsReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	if _, err := infoProvider.GetSubdomainRecord(ConformanceDomain, "mail", "A"); !errors.Is(err, deens.ErrNotFound) {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return deens.ErrNotFound for a missing record type but returned %v", err)
+	}
+}
+
+func testGetDomainRecordsUnknownDomainNotFoundErrorIsReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	if _, err := infoProvider.GetDomainRecords("unknown-" + ConformanceDomain); !errors.Is(err, deens.ErrNotFound) {
+		t.Fail()
+		t.Logf("GetDomainRecords() should return deens.ErrNotFound for an unknown domain but returned %v", err)
+	}
+}
+
+func testInfoProviderContextCancelledContextErrorIsReturned(t *testing.T, infoProvider deens.DNSInfoProvider) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := deens.AsContextDNSInfoProvider(infoProvider).GetDomainRecordsContext(ctx, ConformanceDomain); !errors.Is(err, context.Canceled) {
+		t.Fail()
+		t.Logf("GetDomainRecordsContext() should return context.Canceled but returned %v", err)
+	}
+}
+
+// requireCreateRecord creates the given record and stops the test if
+// that fails.
+func requireCreateRecord(t *testing.T, client deens.DNSClient, changeRecord *dnsimple.ChangeRecord) string {
+	id, err := client.CreateRecord(ConformanceDomain, changeRecord)
+	if err != nil {
+		t.Fatalf("CreateRecord() returned an error: %s", err.Error())
+	}
+
+	return id
+}
+
+// requireRecord returns the record with the given ID and stops the test
+// if it does not exist.
+func requireRecord(t *testing.T, client deens.DNSClient, id string) dnsimple.Record {
+	records, err := client.GetRecords(ConformanceDomain)
+	if err != nil {
+		t.Fatalf("GetRecords() returned an error: %s", err.Error())
+	}
+
+	for _, record := range records {
+		if record.StringId() == id {
+			return record
+		}
+	}
+
+	t.Fatalf("GetRecords() should return the record %q but returned %v", id, records)
+	return dnsimple.Record{}
+}
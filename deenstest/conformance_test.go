@@ -0,0 +1,67 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deenstest
+
+import (
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"testing"
+	"time"
+)
+
+// newConformanceStore creates a fake client that contains the given domain and records.
+func newConformanceStore(t *testing.T, domain string, records []dnsimple.Record) *FakeDNSClient {
+	store := NewFakeDNSClient(domain)
+	for _, record := range records {
+		if _, err := store.AddRecord(domain, record); err != nil {
+			t.Fatalf("AddRecord() returned an error: %s", err.Error())
+		}
+	}
+
+	return store
+}
+
+// newConformanceServerClient creates a DNS client that is connected to a fake server.
+func newConformanceServerClient(t *testing.T, domain string, records []dnsimple.Record) deens.DNSClient {
+	server := NewServer(testCredentials, newConformanceStore(t, domain, records))
+	t.Cleanup(server.Close)
+
+	client, err := deens.NewDNSClient(testCredentials, server.ClientOptions()...)
+	if err != nil {
+		t.Fatalf("NewDNSClient() returned an error: %s", err.Error())
+	}
+
+	return client
+}
+
+func Test_FakeDNSClient_Conformance(t *testing.T) {
+	RunDNSClientTests(t, func(t *testing.T, domain string) deens.DNSClient {
+		return NewFakeDNSClient(domain)
+	})
+}
+
+func Test_Server_DNSClient_Conformance(t *testing.T) {
+	RunDNSClientTests(t, func(t *testing.T, domain string) deens.DNSClient {
+		return newConformanceServerClient(t, domain, nil)
+	})
+}
+
+func Test_DNSInfoProvider_FakeDNSClient_Conformance(t *testing.T) {
+	RunDNSInfoProviderTests(t, func(t *testing.T, domain string, records []dnsimple.Record) deens.DNSInfoProvider {
+		return deens.NewDNSInfoProvider(newConformanceStore(t, domain, records))
+	})
+}
+
+func Test_DNSInfoProvider_Server_Conformance(t *testing.T) {
+	RunDNSInfoProviderTests(t, func(t *testing.T, domain string, records []dnsimple.Record) deens.DNSInfoProvider {
+		return deens.NewDNSInfoProvider(newConformanceServerClient(t, domain, records))
+	})
+}
+
+func Test_CachingDNSInfoProvider_Conformance(t *testing.T) {
+	RunDNSInfoProviderTests(t, func(t *testing.T, domain string, records []dnsimple.Record) deens.DNSInfoProvider {
+		return deens.NewCachingDNSInfoProvider(deens.NewDNSInfoProvider(newConformanceStore(t, domain, records)), time.Minute)
+	})
+}
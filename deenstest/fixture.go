@@ -0,0 +1,331 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deenstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Scrubbed replaces credentials in recorded fixtures.
+const Scrubbed = "[scrubbed]"
+
+// credentialHeaders are the request headers that carry credentials.
+var credentialHeaders = []string{"X-DNSimple-Token", "X-DNSimple-Domain-Token", "Authorization"}
+
+// tokenFieldPattern matches JSON fields that contain tokens
+// (e.g. the domain token in domain responses).
+var tokenFieldPattern = regexp.MustCompile(`"((?:api_|access_)?token)"(\s*):(\s*)"[^"]*"`)
+
+// Interaction is a recorded request together with its response.
+type Interaction struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is a recorded request.
+type FixtureRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// FixtureResponse is a recorded response.
+type FixtureResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// NewRecorder creates a RoundTripper that sends all requests with the
+// given transport (http.DefaultTransport if nil) and records them
+// together with their responses. Credentials are scrubbed from the
+// recorded headers, URLs and bodies. Call Save to write the recorded
+// interactions to the fixture file with the given path.
+//
+// Use deens.WithTransport to record the requests of a DNS client.
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{path: path, transport: transport}
+}
+
+// Recorder is a RoundTripper that records requests and responses.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+
+	lock         sync.Mutex
+	interactions []Interaction
+}
+
+// RoundTrip sends the given request and records it with its response.
+func (recorder *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	outgoing := request.Clone(request.Context())
+	outgoing.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+
+	response, err := recorder.transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := readBody(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	secrets := credentialValues(request.Header)
+	interaction := Interaction{
+		Request: FixtureRequest{
+			Method: request.Method,
+			URL:    scrub(request.URL.String(), secrets),
+			Header: scrubbedCredentialHeaders(request.Header),
+			Body:   scrub(string(requestBody), secrets),
+		},
+		Response: FixtureResponse{
+			StatusCode: response.StatusCode,
+			Header:     scrubHeader(response.Header, secrets),
+			Body:       scrub(string(responseBody), secrets),
+		},
+	}
+
+	recorder.lock.Lock()
+	recorder.interactions = append(recorder.interactions, interaction)
+	recorder.lock.Unlock()
+
+	return response, nil
+}
+
+// Interactions returns the recorded interactions.
+func (recorder *Recorder) Interactions() []Interaction {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	return append([]Interaction(nil), recorder.interactions...)
+}
+
+// Save writes the recorded interactions to the fixture file.
+func (recorder *Recorder) Save() error {
+	content, err := json.MarshalIndent(recorder.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(recorder.path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("Unable to write fixture file %q: %s", recorder.path, err.Error())
+	}
+
+	return nil
+}
+
+// NewReplayer creates a RoundTripper that answers requests with the
+// interactions of the fixture file with the given path. The requests
+// must arrive in the recorded order with the recorded method, URL and
+// body; all other requests fail.
+//
+// Use deens.WithTransport to replay the fixture for a DNS client and
+// call Verify at the end of the test.
+func NewReplayer(path string) (*Replayer, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read fixture file %q: %s", path, err.Error())
+	}
+
+	var interactions []Interaction
+	if err := json.Unmarshal(content, &interactions); err != nil {
+		return nil, fmt.Errorf("Invalid fixture file %q: %s", path, err.Error())
+	}
+
+	return &Replayer{interactions: interactions}, nil
+}
+
+// Replayer is a RoundTripper that replays recorded interactions.
+type Replayer struct {
+	lock         sync.Mutex
+	interactions []Interaction
+	next         int
+	unexpected   error
+}
+
+// RoundTrip returns the recorded response of the given request if it
+// is the next expected request.
+func (replayer *Replayer) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := credentialValues(request.Header)
+	actual := FixtureRequest{
+		Method: request.Method,
+		URL:    scrub(request.URL.String(), secrets),
+		Body:   scrub(string(requestBody), secrets),
+	}
+
+	replayer.lock.Lock()
+	defer replayer.lock.Unlock()
+
+	if replayer.next >= len(replayer.interactions) {
+		return nil, replayer.fail(fmt.Errorf("Unexpected request %s %s: all %d interactions have been replayed", actual.Method, actual.URL, len(replayer.interactions)))
+	}
+
+	expected := replayer.interactions[replayer.next]
+	if actual.Method != expected.Request.Method || actual.URL != expected.Request.URL || !equalBodies(actual.Body, expected.Request.Body) {
+		return nil, replayer.fail(fmt.Errorf("Unexpected request %s %s %s: expected %s %s %s", actual.Method, actual.URL, actual.Body, expected.Request.Method, expected.Request.URL, expected.Request.Body))
+	}
+
+	replayer.next++
+
+	header := expected.Response.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", expected.Response.StatusCode, http.StatusText(expected.Response.StatusCode)),
+		StatusCode:    expected.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(expected.Response.Body)),
+		ContentLength: int64(len(expected.Response.Body)),
+		Request:       request,
+	}, nil
+}
+
+// Verify returns an error if an unexpected request was made or if not
+// all interactions have been replayed.
+func (replayer *Replayer) Verify() error {
+	replayer.lock.Lock()
+	defer replayer.lock.Unlock()
+
+	if replayer.unexpected != nil {
+		return replayer.unexpected
+	}
+
+	if remaining := len(replayer.interactions) - replayer.next; remaining > 0 {
+		next := replayer.interactions[replayer.next].Request
+		return fmt.Errorf("%d interactions have not been replayed (next: %s %s)", remaining, next.Method, next.URL)
+	}
+
+	return nil
+}
+
+// fail remembers the first unexpected request.
+func (replayer *Replayer) fail(err error) error {
+	if replayer.unexpected == nil {
+		replayer.unexpected = err
+	}
+
+	return err
+}
+
+// readBody reads and closes the given body. A nil body is empty.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// credentialValues returns the credentials contained in the given
+// request headers.
+func credentialValues(header http.Header) []string {
+	var secrets []string
+	for _, name := range credentialHeaders {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+
+		value = strings.TrimPrefix(value, "Bearer ")
+		secrets = append(secrets, value)
+
+		// X-DNSimple-Token contains the email address and the API token
+		if parts := strings.SplitN(value, ":", 2); len(parts) == 2 {
+			secrets = append(secrets, parts...)
+		}
+	}
+
+	return secrets
+}
+
+// scrubbedCredentialHeaders returns the credential headers of the given
+// request headers with scrubbed values.
+func scrubbedCredentialHeaders(header http.Header) http.Header {
+	scrubbed := make(http.Header)
+	for _, name := range credentialHeaders {
+		if header.Get(name) != "" {
+			scrubbed.Set(name, Scrubbed)
+		}
+	}
+
+	if len(scrubbed) == 0 {
+		return nil
+	}
+
+	return scrubbed
+}
+
+// scrubHeader removes the given secrets from the values of the given headers.
+func scrubHeader(header http.Header, secrets []string) http.Header {
+	scrubbed := make(http.Header)
+	for name, values := range header {
+		for _, value := range values {
+			scrubbed.Add(name, scrub(value, secrets))
+		}
+	}
+
+	return scrubbed
+}
+
+// scrub replaces the given secrets and all JSON token fields in the
+// given text.
+func scrub(text string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.Replace(text, secret, Scrubbed, -1)
+		}
+	}
+
+	return tokenFieldPattern.ReplaceAllString(text, `"$1"$2:$3"`+Scrubbed+`"`)
+}
+
+// equalBodies checks whether the given request bodies are equal. JSON
+// bodies are compared by value.
+func equalBodies(actual, expected string) bool {
+	if actual == expected {
+		return true
+	}
+
+	var actualValue, expectedValue interface{}
+	if json.Unmarshal([]byte(actual), &actualValue) != nil || json.Unmarshal([]byte(expected), &expectedValue) != nil {
+		return false
+	}
+
+	actualJSON, _ := json.Marshal(actualValue)
+	expectedJSON, _ := json.Marshal(expectedValue)
+	return bytes.Equal(actualJSON, expectedJSON)
+}
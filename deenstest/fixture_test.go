@@ -0,0 +1,134 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deenstest
+
+import (
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordFixture records an UpdateSubdomain call of the DNSEditor
+// against a fake server and saves it in a fixture file.
+func recordFixture(t *testing.T, path string) {
+	server := NewServer(testCredentials, NewFakeDNSClient("example.com"))
+	defer server.Close()
+
+	server.Store().AddRecord("example.com", dnsimple.Record{Name: "www", RecordType: "A", Content: "127.0.0.1", Ttl: 600})
+
+	recorder := NewRecorder(path, server.Client().Transport)
+	client, _ := deens.NewDNSClient(testCredentials, deens.WithBaseURL(server.URL), deens.WithTransport(recorder))
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client))
+
+	if err := editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatalf("UpdateSubdomain() returned an error while recording: %s", err.Error())
+	}
+
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() returned an error: %s", err.Error())
+	}
+}
+
+// newReplayClient creates a DNS client that replays the given fixture
+// with the base URL of the recording.
+func newReplayClient(t *testing.T, path string) (*Replayer, deens.DNSClient) {
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("NewReplayer() returned an error: %s", err.Error())
+	}
+
+	interactions := replayer.interactions
+	baseURL := interactions[0].Request.URL[:strings.Index(interactions[0].Request.URL, "/domains")]
+	client, _ := deens.NewDNSClient(testCredentials, deens.WithBaseURL(baseURL), deens.WithTransport(replayer))
+	return replayer, client
+}
+
+// Recorded fixtures must not contain any credentials.
+func Test_Recorder_Save_CredentialsAreScrubbed(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "update.json")
+
+	// act
+	recordFixture(t, path)
+
+	// assert
+	content, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(content), testCredentials.Token) || strings.Contains(string(content), testCredentials.Email) {
+		t.Fail()
+		t.Logf("The fixture should not contain the credentials: %s", content)
+	}
+
+	if !strings.Contains(string(content), `"X-Dnsimple-Token": [`) || !strings.Contains(string(content), Scrubbed) {
+		t.Fail()
+		t.Logf("The fixture should contain the scrubbed token header: %s", content)
+	}
+}
+
+// Token fields in response bodies should be scrubbed.
+func Test_Scrub_TokenFields_ValuesAreReplaced(t *testing.T) {
+	// arrange
+	input := `{"domain":{"name":"example.com","token":"DoMaInToKeN"},"access_token": "abc"}`
+
+	// act
+	result := scrub(input, nil)
+
+	// assert
+	if strings.Contains(result, "DoMaInToKeN") || strings.Contains(result, "abc") {
+		t.Fail()
+		t.Logf("scrub(%q) should replace the token fields but returned %q", input, result)
+	}
+}
+
+// A recorded DNSEditor flow should be replayed without a server.
+func Test_Replayer_RecordedFlow_IsReplayed(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "update.json")
+	recordFixture(t, path)
+
+	replayer, client := newReplayClient(t, path)
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client))
+
+	// act
+	err := editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.2"))
+
+	// assert
+	if err != nil {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should succeed with the replayed responses but returned %s", err.Error())
+	}
+
+	if err := replayer.Verify(); err != nil {
+		t.Fail()
+		t.Logf("Verify() should succeed but returned %s", err.Error())
+	}
+}
+
+// Requests that were not recorded should fail.
+func Test_Replayer_UnexpectedRequest_ErrorIsReturned(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "update.json")
+	recordFixture(t, path)
+
+	replayer, client := newReplayClient(t, path)
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client))
+
+	// act
+	err := editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.3"))
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should fail because the update was not recorded")
+	}
+
+	if err := replayer.Verify(); err == nil || !strings.Contains(err.Error(), "Unexpected request PUT") {
+		t.Fail()
+		t.Logf("Verify() should report the unexpected request but returned %v", err)
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deenstest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewServer starts a local stand-in for the DNSimple API v1 that
+// accepts the given account credentials and keeps its domains and
+// records in the given fake client. Use the fake client to add domains
+// and records, to inspect the results and to inject errors; injected
+// *deens.APIError values are returned with their status code.
+//
+// Connect a DNS client with the options returned by ClientOptions and
+// call Close when the server is no longer needed.
+func NewServer(credentials deens.APICredentials, store *FakeDNSClient) *Server {
+	server := &Server{
+		store:        store,
+		credentials:  credentials,
+		domainTokens: make(map[string]string),
+	}
+
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	return server
+}
+
+// Server is a fake DNSimple API v1 server. It implements the user,
+// domain and record endpoints used by the deens package with the JSON
+// formats of the real API.
+type Server struct {
+	*httptest.Server
+
+	store       *FakeDNSClient
+	credentials deens.APICredentials
+
+	lock         sync.Mutex
+	domainTokens map[string]string
+	latency      time.Duration
+}
+
+// ClientOptions returns the options that connect a DNS client created
+// with deens.NewDNSClient to this server.
+func (server *Server) ClientOptions() []deens.ClientOption {
+	return []deens.ClientOption{
+		deens.WithBaseURL(server.URL),
+		deens.WithHTTPClient(server.Client()),
+	}
+}
+
+// Store returns the fake client that contains the domains and records
+// of this server.
+func (server *Server) Store() *FakeDNSClient {
+	return server.store
+}
+
+// AddDomainToken allows the given domain token to access the given domain.
+func (server *Server) AddDomainToken(domain, token string) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.domainTokens[token] = normalizeName(domain)
+}
+
+// SetLatency delays every response by the given duration.
+func (server *Server) SetLatency(latency time.Duration) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.latency = latency
+}
+
+// serveHTTP handles all requests of the server.
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	server.lock.Lock()
+	latency := server.latency
+	server.lock.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// user
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(path) == 1 && path[0] == "user" && r.Method == "GET" {
+		server.getUser(w, r)
+		return
+	}
+
+	// domains/{domain}/records/{id}
+	if path[0] != "domains" || len(path) > 4 || (len(path) > 2 && path[2] != "records") {
+		writeError(w, http.StatusNotFound, "Not found", nil)
+		return
+	}
+
+	domain := ""
+	if len(path) > 1 {
+		domain = path[1]
+	}
+
+	permittedDomain, authenticated := server.authenticate(r)
+	if !authenticated || (permittedDomain != "" && domain != "" && normalizeName(domain) != permittedDomain) {
+		writeError(w, http.StatusUnauthorized, "Authentication failed", nil)
+		return
+	}
+
+	switch {
+	case len(path) == 1 && r.Method == "GET":
+		server.listDomains(w, permittedDomain)
+	case len(path) == 2 && r.Method == "GET":
+		server.getDomain(w, domain)
+	case len(path) == 3 && r.Method == "GET":
+		server.listRecords(w, domain)
+	case len(path) == 3 && r.Method == "POST":
+		server.createRecord(w, r, domain)
+	case len(path) == 4 && r.Method == "GET":
+		server.getRecord(w, domain, path[3])
+	case len(path) == 4 && r.Method == "PUT":
+		server.updateRecord(w, r, domain, path[3])
+	case len(path) == 4 && r.Method == "DELETE":
+		server.destroyRecord(w, domain, path[3])
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+	}
+}
+
+// authenticate checks the credentials of the given request. For domain
+// tokens the permitted domain is returned.
+func (server *Server) authenticate(r *http.Request) (string, bool) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if token := r.Header.Get("X-DNSimple-Domain-Token"); token != "" {
+		domain, ok := server.domainTokens[token]
+		return domain, ok
+	}
+
+	expected := server.credentials.Email + ":" + server.credentials.Token
+	return "", r.Header.Get("X-DNSimple-Token") == expected
+}
+
+// getUser returns the user of the account credentials. Domain tokens
+// cannot read the user.
+func (server *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	permittedDomain, authenticated := server.authenticate(r)
+	if !authenticated || permittedDomain != "" {
+		writeError(w, http.StatusUnauthorized, "Authentication failed", nil)
+		return
+	}
+
+	var response struct {
+		User struct {
+			ID    int    `json:"id"`
+			Email string `json:"email"`
+		} `json:"user"`
+	}
+
+	response.User.ID = 1
+	response.User.Email = server.credentials.Email
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (server *Server) listDomains(w http.ResponseWriter, permittedDomain string) {
+	domains, err := server.store.GetDomains()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	responses := []dnsimple.DomainResponse{}
+	for _, domain := range domains {
+		if permittedDomain != "" && domain.Name != permittedDomain {
+			continue
+		}
+
+		responses = append(responses, dnsimple.DomainResponse{Domain: domain})
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (server *Server) getDomain(w http.ResponseWriter, name string) {
+	domains, err := server.store.GetDomains()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	for _, domain := range domains {
+		if domain.Name == normalizeName(name) {
+			writeJSON(w, http.StatusOK, dnsimple.DomainResponse{Domain: domain})
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Sprintf("Domain `%s` not found", name), nil)
+}
+
+func (server *Server) listRecords(w http.ResponseWriter, domain string) {
+	records, err := server.store.GetRecords(domain)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	responses := []dnsimple.RecordResponse{}
+	for _, record := range records {
+		responses = append(responses, dnsimple.RecordResponse{Record: record})
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (server *Server) getRecord(w http.ResponseWriter, domain, id string) {
+	records, err := server.store.GetRecords(domain)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	for _, record := range records {
+		if record.StringId() == id {
+			writeJSON(w, http.StatusOK, dnsimple.RecordResponse{Record: record})
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Sprintf("Record `%s` not found", id), nil)
+}
+
+func (server *Server) createRecord(w http.ResponseWriter, r *http.Request, domain string) {
+	changeRecord, priority, err := readChangeRecord(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	var id string
+	if priority != nil {
+		id, err = server.store.CreatePriorityRecordContext(r.Context(), domain, changeRecord, *priority)
+	} else {
+		id, err = server.store.CreateRecordContext(r.Context(), domain, changeRecord)
+	}
+
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	server.writeRecord(w, http.StatusCreated, domain, id)
+}
+
+func (server *Server) updateRecord(w http.ResponseWriter, r *http.Request, domain, id string) {
+	changeRecord, priority, err := readChangeRecord(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if priority != nil {
+		_, err = server.store.UpdatePriorityRecordContext(r.Context(), domain, id, changeRecord, *priority)
+	} else {
+		_, err = server.store.UpdateRecordContext(r.Context(), domain, id, changeRecord)
+	}
+
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	server.writeRecord(w, http.StatusOK, domain, id)
+}
+
+func (server *Server) destroyRecord(w http.ResponseWriter, domain, id string) {
+	if err := server.store.DestroyRecord(domain, id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// writeRecord writes the record with the given ID as the response.
+func (server *Server) writeRecord(w http.ResponseWriter, statusCode int, domain, id string) {
+	for _, record := range server.store.Records(domain) {
+		if record.StringId() == id {
+			writeJSON(w, statusCode, dnsimple.RecordResponse{Record: record})
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Sprintf("Record `%s` not found", id), nil)
+}
+
+// readChangeRecord reads the record parameters and the optional priority
+// from the body of the given request. The parameters can be wrapped in a
+// "record" object.
+func readChangeRecord(r *http.Request) (*dnsimple.ChangeRecord, *int, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type recordParameters struct {
+		Name       *string      `json:"name"`
+		RecordType string       `json:"record_type"`
+		Content    string       `json:"content"`
+		TTL        *json.Number `json:"ttl"`
+		Prio       *int         `json:"prio"`
+	}
+
+	var parameters struct {
+		recordParameters
+		Record *recordParameters `json:"record"`
+	}
+
+	if err := json.Unmarshal(body, &parameters); err != nil {
+		return nil, nil, fmt.Errorf("Invalid request body: %s", err.Error())
+	}
+
+	record := parameters.recordParameters
+	if parameters.Record != nil {
+		record = *parameters.Record
+	}
+
+	changeRecord := &dnsimple.ChangeRecord{Type: record.RecordType, Value: record.Content}
+	if record.Name != nil {
+		changeRecord.Name = *record.Name
+	}
+
+	if record.TTL != nil {
+		changeRecord.Ttl = record.TTL.String()
+	}
+
+	return changeRecord, record.Prio, nil
+}
+
+// writeStoreError writes the given error of the fake client as response.
+// API errors keep their status code; all other errors are server errors.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var apiError *deens.APIError
+	if !errors.As(err, &apiError) {
+		writeError(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	if apiError.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(apiError.RetryAfter/time.Second)))
+	}
+
+	writeError(w, apiError.StatusCode, apiError.Message, apiError.Errors)
+}
+
+// writeError writes an error response in the format of the DNSimple API.
+func writeError(w http.ResponseWriter, statusCode int, message string, validationErrors map[string][]string) {
+	response := struct {
+		dnsimple.DNSimpleError
+		Message string `json:"message,omitempty"`
+	}{dnsimple.DNSimpleError{Errors: validationErrors}, message}
+
+	writeJSON(w, statusCode, response)
+}
+
+// writeJSON writes the given value as JSON response.
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(value)
+}
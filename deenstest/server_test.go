@@ -0,0 +1,210 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deenstest
+
+import (
+	"context"
+	"errors"
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"testing"
+	"time"
+)
+
+var testCredentials = deens.APICredentials{Email: "john.doe@example.com", Token: "ApItOken"}
+
+// newTestServer starts a server with the domain example.com and returns
+// it together with a DNS client that is connected to it.
+func newTestServer(t *testing.T) (*Server, deens.DNSClient) {
+	server := NewServer(testCredentials, NewFakeDNSClient("example.com"))
+	client, err := deens.NewDNSClient(testCredentials, server.ClientOptions()...)
+	if err != nil {
+		server.Close()
+		t.Fatalf("NewDNSClient() returned an error: %s", err.Error())
+	}
+
+	return server, client
+}
+
+// The DNSEditor should be able to create, update and delete records on the server.
+func Test_Server_DNSEditor_RecordsAreChanged(t *testing.T) {
+	// arrange
+	server, client := newTestServer(t)
+	defer server.Close()
+
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client))
+	server.Store().AddRecord("example.com", dnsimple.Record{Name: "www", RecordType: "A", Content: "127.0.0.1", Ttl: 600})
+
+	// act
+	updateError := editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.2"))
+	recordsAfterUpdate := server.Store().Records("example.com")
+
+	deleteError := editor.DeleteSubdomain("example.com", "www", "A")
+	recordsAfterDelete := server.Store().Records("example.com")
+
+	// assert
+	if updateError != nil || len(recordsAfterUpdate) != 1 || recordsAfterUpdate[0].Content != "127.0.0.2" {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should update the record but returned %v (records: %v)", updateError, recordsAfterUpdate)
+	}
+
+	if deleteError != nil || len(recordsAfterDelete) != 0 {
+		t.Fail()
+		t.Logf("DeleteSubdomain() should delete the record but returned %v (records: %v)", deleteError, recordsAfterDelete)
+	}
+}
+
+// Records created through the API should be stored with their TTL.
+func Test_Server_CreateRecord_RecordIsStored(t *testing.T) {
+	// arrange
+	server, client := newTestServer(t)
+	defer server.Close()
+
+	// act
+	id, err := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "AAAA", Value: "::1", Ttl: "300"})
+
+	// assert
+	records := server.Store().Records("example.com")
+	if err != nil || len(records) != 1 || records[0].StringId() != id || records[0].Ttl != 300 {
+		t.Fail()
+		t.Logf("CreateRecord() should store the record but returned %q, %v (records: %v)", id, err, records)
+	}
+}
+
+// MX records created through the DNSEditor should be stored with their priority.
+func Test_Server_DNSEditor_MXRecordIsStoredWithPriority(t *testing.T) {
+	// arrange
+	server, client := newTestServer(t)
+	defer server.Close()
+
+	editor := deens.NewDNSEditor(client, deens.NewDNSInfoProvider(client)).(deens.DNSTypedRecordEditor)
+
+	// act
+	_, err := editor.CreateRecord("example.com", "", 3600, deens.MXRecord{Priority: 10, Host: "mx.example.com"})
+
+	// assert
+	records := server.Store().Records("example.com")
+	if err != nil || len(records) != 1 || records[0].RecordType != "MX" || records[0].Content != "mx.example.com" || records[0].Prio != 10 {
+		t.Fail()
+		t.Logf("CreateRecord() should store the MX record with priority 10 but returned %v (records: %v)", err, records)
+	}
+}
+
+// Requests with wrong credentials should be rejected.
+func Test_Server_WrongCredentials_AuthenticationErrorIsReturned(t *testing.T) {
+	// arrange
+	server := NewServer(testCredentials, NewFakeDNSClient("example.com"))
+	defer server.Close()
+
+	client, _ := deens.NewDNSClient(deens.APICredentials{Email: "john.doe@example.com", Token: "wrong"}, server.ClientOptions()...)
+
+	// act
+	_, err := client.GetDomains()
+
+	// assert
+	if !errors.Is(err, deens.ErrAuthentication) {
+		t.Fail()
+		t.Logf("GetDomains() should return an authentication error but returned %v", err)
+	}
+}
+
+// Duplicate records should be rejected with the validation errors of the API.
+func Test_Server_DuplicateRecord_ValidationErrorIsReturned(t *testing.T) {
+	// arrange
+	server, client := newTestServer(t)
+	defer server.Close()
+
+	changeRecord := &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"}
+	client.CreateRecord("example.com", changeRecord)
+
+	// act
+	_, err := client.CreateRecord("example.com", changeRecord)
+
+	// assert
+	var apiError *deens.APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != 422 || len(apiError.Errors["base"]) != 1 {
+		t.Fail()
+		t.Logf("CreateRecord() should return a 422 error with validation errors but returned %v", err)
+	}
+}
+
+// Account credentials should be verified with the user endpoint.
+func Test_Server_AccountCredentials_UserIsReported(t *testing.T) {
+	// arrange
+	server := NewServer(testCredentials, NewFakeDNSClient("example.com"))
+	defer server.Close()
+
+	// act
+	verification, err := deens.VerifyCredentials(testCredentials, server.ClientOptions()...)
+
+	// assert
+	if err != nil || !verification.Valid || verification.AccountID != 1 || verification.Email != testCredentials.Email || len(verification.Domains) != 1 {
+		t.Fail()
+		t.Logf("VerifyCredentials() should report the user and its domain but returned %+v, %v", verification, err)
+	}
+}
+
+// Domain tokens should only be accepted for their own domain.
+func Test_Server_DomainToken_OtherDomainIsRejected(t *testing.T) {
+	// arrange
+	server := NewServer(testCredentials, NewFakeDNSClient("example.com", "example.org"))
+	defer server.Close()
+
+	server.AddDomainToken("example.com", "DoMaInToKeN")
+	verification, err := deens.VerifyCredentials(deens.DomainTokenCredentials{Domain: "example.com", Token: "DoMaInToKeN"}, server.ClientOptions()...)
+	if err != nil || !verification.Valid {
+		t.Fatalf("VerifyCredentials() should accept the domain token but returned %+v, %v", verification, err)
+	}
+
+	client, _ := deens.NewDNSClient(deens.DomainTokenCredentials{Domain: "example.org", Token: "DoMaInToKeN"}, server.ClientOptions()...)
+
+	// act
+	_, err = client.GetRecords("example.org")
+
+	// assert
+	if !errors.Is(err, deens.ErrAuthentication) {
+		t.Fail()
+		t.Logf("GetRecords() should reject the token for another domain but returned %v", err)
+	}
+}
+
+// Slow responses should be aborted by the context of the caller.
+func Test_Server_Latency_ContextDeadlineIsExceeded(t *testing.T) {
+	// arrange
+	server, client := newTestServer(t)
+	defer server.Close()
+
+	server.SetLatency(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// act
+	_, err := deens.AsContextDNSClient(client).GetRecordsContext(ctx, "example.com")
+
+	// assert
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fail()
+		t.Logf("GetRecordsContext() should return context.DeadlineExceeded but returned %v", err)
+	}
+}
+
+// Injected API errors should be returned with their status code.
+func Test_Server_InjectedAPIError_StatusCodeIsReturned(t *testing.T) {
+	// arrange
+	server, client := newTestServer(t)
+	defer server.Close()
+
+	server.Store().FailNext(MethodGetRecords, &deens.APIError{StatusCode: 503, Message: "Maintenance"})
+
+	// act
+	_, err := client.GetRecords("example.com")
+
+	// assert
+	if !errors.Is(err, deens.ErrServer) {
+		t.Fail()
+		t.Logf("GetRecords() should return a server error but returned %v", err)
+	}
+}
@@ -5,18 +5,81 @@
 package deens
 
 import (
+	"context"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 )
 
 // NewDNSClient creates a new DNS client instance for the given credentials.
-func NewDNSClient(credentials APICredentials) (DNSClient, error) {
-	dnsimpleClient, dnsimpleClientError := dnsimple.NewClient(credentials.Email, credentials.Token)
-	if dnsimpleClientError != nil {
-		return nil, fmt.Errorf("Unable to create DNSimple client. Error: %s", dnsimpleClientError.Error())
+// APICredentials and DomainTokenCredentials use the DNSimple API v1,
+// AccessTokenCredentials use the DNSimple API v2. Clients created with
+// DomainTokenCredentials can only access the domain the token belongs
+// to. Operations on other domains fail with ErrDomainNotPermitted
+// without sending a request.
+//
+// The HTTP client, the base URL, the user agent and the timeout can be
+// changed with the given options.
+//
+// The returned client also implements the ContextDNSClient, the
+// RecordPager, the PriorityRecordWriter and the RateLimitReporter
+// interface. Clients for the API v2 also implement the RecordFilterer
+// interface.
+func NewDNSClient(credentials Credentials, options ...ClientOption) (DNSClient, error) {
+	if accessTokenCredentials, ok := credentials.(AccessTokenCredentials); ok {
+		client, clientError := newV2ClientForCredentials(accessTokenCredentials, options...)
+		if clientError != nil {
+			return nil, fmt.Errorf("Unable to create DNSimple client. Error: %s", clientError.Error())
+		}
+
+		return client, nil
+	}
+
+	client, clientError := newV1ClientForCredentials(credentials, options...)
+	if clientError != nil {
+		return nil, fmt.Errorf("Unable to create DNSimple client. Error: %s", clientError.Error())
 	}
 
-	return dnsimpleClient, nil
+	if domainCredentials, ok := credentials.(DomainTokenCredentials); ok {
+		return newDomainScopedClient(domainCredentials.Domain, client), nil
+	}
+
+	return client, nil
+}
+
+// newV1ClientForCredentials creates a DNSimple API v1 client for the
+// given credentials and options.
+func newV1ClientForCredentials(credentials Credentials, options ...ClientOption) (*v1Client, error) {
+	settings, err := newClientOptions(DefaultBaseURL, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsimpleClient, err := newDNSimpleClient(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsimpleClient.URL = settings.baseURL
+	dnsimpleClient.Http = settings.newHTTPClient()
+
+	return newV1Client(dnsimpleClient, settings.userAgent), nil
+}
+
+// newDNSimpleClient creates a DNSimple API client for the given credentials.
+func newDNSimpleClient(credentials Credentials) (*dnsimple.Client, error) {
+	switch credentials := credentials.(type) {
+	case APICredentials:
+		return dnsimple.NewClient(credentials.Email, credentials.Token)
+
+	case DomainTokenCredentials:
+		if !isValidDomain(credentials.Domain) {
+			return nil, fmt.Errorf("The domain name is invalid: %q", credentials.Domain)
+		}
+
+		return dnsimple.NewClientWithDomainToken(credentials.Token)
+	}
+
+	return nil, fmt.Errorf("Unsupported credentials: %T", credentials)
 }
 
 // DNSClient provides functions for updating DNS records.
@@ -36,3 +99,84 @@ type DNSClient interface {
 	// DestroyRecord deletes the DNS record with the given id.
 	DestroyRecord(domain string, id string) error
 }
+
+// ContextDNSClient provides functions for updating DNS records that
+// can be cancelled with a context.
+type ContextDNSClient interface {
+	// UpdateRecordContext update the DNS record with the given id.
+	UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error)
+
+	// GetRecordsContext returns all DNS records for the given domain.
+	GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error)
+
+	// GetDomainsContext returns a list of domain.
+	GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error)
+
+	// CreateRecordContext creates a new DNS record for the given domain.
+	CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error)
+
+	// DestroyRecordContext deletes the DNS record with the given id.
+	DestroyRecordContext(ctx context.Context, domain string, id string) error
+}
+
+// AsContextDNSClient returns the given client as a ContextDNSClient.
+// Clients that do not support contexts natively are wrapped in an
+// adapter that checks the context before each call but cannot abort
+// calls that are already in progress.
+func AsContextDNSClient(client DNSClient) ContextDNSClient {
+	if contextClient, ok := client.(ContextDNSClient); ok {
+		return contextClient
+	}
+
+	return &contextDNSClientAdapter{client}
+}
+
+// contextDNSClientAdapter adds context support to a DNSClient.
+type contextDNSClientAdapter struct {
+	client DNSClient
+}
+
+// UpdateRecordContext update the DNS record with the given id.
+func (adapter *contextDNSClientAdapter) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return adapter.client.UpdateRecord(domain, id, opts)
+}
+
+// GetRecordsContext returns all DNS records for the given domain.
+func (adapter *contextDNSClientAdapter) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return adapter.client.GetRecords(domain)
+}
+
+// GetDomainsContext returns a list of domain.
+func (adapter *contextDNSClientAdapter) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return adapter.client.GetDomains()
+}
+
+// CreateRecordContext creates a new DNS record for the given domain.
+func (adapter *contextDNSClientAdapter) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return adapter.client.CreateRecord(domain, opts)
+}
+
+// DestroyRecordContext deletes the DNS record with the given id.
+func (adapter *contextDNSClientAdapter) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return adapter.client.DestroyRecord(domain, id)
+}
@@ -0,0 +1,186 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net/url"
+	"strings"
+)
+
+// ErrDomainNotPermitted is returned by clients that were created with
+// DomainTokenCredentials if an operation targets a different domain.
+var ErrDomainNotPermitted = errors.New("The credentials do not grant access to this domain")
+
+// newDomainScopedClient creates a DNSClient that only allows operations
+// on the given domain.
+func newDomainScopedClient(domain string, client DNSClient) *domainScopedClient {
+	return &domainScopedClient{normalizeDomain(domain), AsContextDNSClient(client)}
+}
+
+// domainScopedClient rejects all operations that do not target its
+// domain before they reach the wrapped client.
+type domainScopedClient struct {
+	domain string
+	client ContextDNSClient
+}
+
+// UpdateRecord update the DNS record with the given id.
+func (scopedClient *domainScopedClient) UpdateRecord(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return scopedClient.UpdateRecordContext(context.Background(), domain, id, opts)
+}
+
+// GetRecords returns all DNS records for the given domain.
+func (scopedClient *domainScopedClient) GetRecords(domain string) ([]dnsimple.Record, error) {
+	return scopedClient.GetRecordsContext(context.Background(), domain)
+}
+
+// GetDomains returns the domain the client is restricted to.
+func (scopedClient *domainScopedClient) GetDomains() ([]dnsimple.Domain, error) {
+	return scopedClient.GetDomainsContext(context.Background())
+}
+
+// CreateRecord creates a new DNS record for the given domain.
+func (scopedClient *domainScopedClient) CreateRecord(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return scopedClient.CreateRecordContext(context.Background(), domain, opts)
+}
+
+// DestroyRecord deletes the DNS record with the given id.
+func (scopedClient *domainScopedClient) DestroyRecord(domain string, id string) error {
+	return scopedClient.DestroyRecordContext(context.Background(), domain, id)
+}
+
+// UpdateRecordContext update the DNS record with the given id.
+func (scopedClient *domainScopedClient) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	return scopedClient.client.UpdateRecordContext(ctx, domain, id, opts)
+}
+
+// GetRecordsContext returns all DNS records for the given domain.
+func (scopedClient *domainScopedClient) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return scopedClient.client.GetRecordsContext(ctx, domain)
+}
+
+// GetRecordsPage returns the given page of DNS records for the given domain.
+func (scopedClient *domainScopedClient) GetRecordsPage(ctx context.Context, domain, page string) (RecordPage, error) {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return RecordPage{}, err
+	}
+
+	if err := scopedClient.checkPage(page); err != nil {
+		return RecordPage{}, err
+	}
+
+	return getRecordsPage(ctx, scopedClient.client, domain, page)
+}
+
+// GetFilteredRecordsContext returns the DNS records of the given domain
+// that match the given filter.
+func (scopedClient *domainScopedClient) GetFilteredRecordsContext(ctx context.Context, domain string, filter RecordFilter) ([]dnsimple.Record, error) {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return getFilteredRecords(ctx, scopedClient.client, domain, filter)
+}
+
+// GetDomainsContext returns the domain the client is restricted to.
+// Domain tokens cannot list the domains of an account.
+func (scopedClient *domainScopedClient) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return []dnsimple.Domain{
+		dnsimple.Domain{Name: scopedClient.domain},
+	}, nil
+}
+
+// CreateRecordContext creates a new DNS record for the given domain.
+func (scopedClient *domainScopedClient) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	return scopedClient.client.CreateRecordContext(ctx, domain, opts)
+}
+
+// CreatePriorityRecordContext creates a new DNS record with the given
+// priority for the given domain.
+func (scopedClient *domainScopedClient) CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	return createPriorityRecord(ctx, scopedClient.client, domain, opts, priority)
+}
+
+// UpdatePriorityRecordContext updates the DNS record with the given id
+// and sets its priority.
+func (scopedClient *domainScopedClient) UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	return updatePriorityRecord(ctx, scopedClient.client, domain, id, opts, priority)
+}
+
+// DestroyRecordContext deletes the DNS record with the given id.
+func (scopedClient *domainScopedClient) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	domain, err := scopedClient.checkDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	return scopedClient.client.DestroyRecordContext(ctx, domain, id)
+}
+
+// RateLimit returns the quota reported by the wrapped client.
+func (scopedClient *domainScopedClient) RateLimit() (RateLimit, bool) {
+	return rateLimitOf(scopedClient.client)
+}
+
+// checkDomain returns ErrDomainNotPermitted if the given domain is not
+// the domain of this client. Otherwise it returns the normalized domain.
+func (scopedClient *domainScopedClient) checkDomain(domain string) (string, error) {
+	if normalizeDomain(domain) != scopedClient.domain {
+		return "", fmt.Errorf("Cannot access %q with a token for %q: %w", domain, scopedClient.domain, ErrDomainNotPermitted)
+	}
+
+	return scopedClient.domain, nil
+}
+
+// checkPage returns ErrDomainNotPermitted if the given page is not a
+// page of the records of the domain of this client. Pages are the
+// endpoints of the next page links (e.g. "/domains/example.com/records?page=2").
+func (scopedClient *domainScopedClient) checkPage(page string) error {
+	if page == "" {
+		return nil
+	}
+
+	pageURL, err := url.Parse(page)
+	if err != nil || pageURL.Scheme != "" || pageURL.Host != "" || !strings.EqualFold(pageURL.Path, "/domains/"+scopedClient.domain+"/records") {
+		return fmt.Errorf("Cannot access the page %q with a token for %q: %w", page, scopedClient.domain, ErrDomainNotPermitted)
+	}
+
+	return nil
+}
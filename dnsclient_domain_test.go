@@ -0,0 +1,130 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"github.com/pearkes/dnsimple"
+	"testing"
+)
+
+// NewDNSClient should restrict clients created with domain tokens to their domain.
+func Test_NewDNSClient_DomainTokenCredentials_ClientIsRestrictedToDomain(t *testing.T) {
+	// arrange
+	credentials, _ := NewDomainTokenCredentials("example.com", "DoMaInToKeN")
+
+	// act
+	client, err := NewDNSClient(credentials)
+
+	// assert
+	if err != nil {
+		t.Fatalf("NewDNSClient() returned an error: %s", err.Error())
+	}
+
+	if _, ok := client.(*domainScopedClient); !ok {
+		t.Fail()
+		t.Logf("NewDNSClient() should return a domain-scoped client for domain token credentials but returned %T", client)
+	}
+}
+
+// Operations on other domains should fail without reaching the wrapped client.
+func Test_domainScopedClient_OtherDomain_ErrDomainNotPermittedIsReturned(t *testing.T) {
+	// arrange
+	requestSent := false
+	dnsClient := &testDNSClient{
+		getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			requestSent = true
+			return nil, nil
+		},
+		createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+			requestSent = true
+			return "1", nil
+		},
+		updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+			requestSent = true
+			return "1", nil
+		},
+		destroyRecordFunc: func(domain string, id string) error {
+			requestSent = true
+			return nil
+		},
+	}
+
+	client := newDomainScopedClient("example.com", dnsClient)
+
+	// act
+	_, getError := client.GetRecords("example.org")
+	_, createError := client.CreateRecord("example.org", &dnsimple.ChangeRecord{})
+	_, updateError := client.UpdateRecord("example.org", "1", &dnsimple.ChangeRecord{})
+	destroyError := client.DestroyRecord("example.org", "1")
+
+	// assert
+	for _, err := range []error{getError, createError, updateError, destroyError} {
+		if !errors.Is(err, ErrDomainNotPermitted) {
+			t.Fail()
+			t.Logf("Operations on example.org should return ErrDomainNotPermitted but returned %v", err)
+		}
+	}
+
+	if requestSent {
+		t.Fail()
+		t.Logf("Operations on example.org must not reach the wrapped client")
+	}
+}
+
+// Operations on the scoped domain should be passed to the wrapped client.
+func Test_domainScopedClient_SameDomain_OperationIsPassedOn(t *testing.T) {
+	// arrange
+	var requestedDomain string
+	dnsClient := &testDNSClient{
+		getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			requestedDomain = domain
+			return []dnsimple.Record{dnsimple.Record{Name: "www"}}, nil
+		},
+	}
+
+	client := newDomainScopedClient("example.com", dnsClient)
+
+	// act
+	records, err := client.GetRecords("Example.com.")
+
+	// assert
+	if err != nil || len(records) != 1 {
+		t.Fail()
+		t.Logf("GetRecords() should have returned the records of the wrapped client but returned %v, %v", records, err)
+	}
+
+	if requestedDomain != "example.com" {
+		t.Fail()
+		t.Logf("GetRecords() should pass the normalized domain to the wrapped client but passed %q", requestedDomain)
+	}
+}
+
+// Pages of other domains should be rejected even if the domain argument is permitted.
+func Test_domainScopedClient_GetRecordsPage_PageOfOtherDomain_ErrDomainNotPermittedIsReturned(t *testing.T) {
+	// arrange
+	client := newDomainScopedClient("example.com", &testDNSClient{})
+	inputs := map[string]bool{
+		"/domains/example.com/records?page=2":                        true,
+		"/domains/Example.com/records?page=2":                        true,
+		"/domains/example.org/records?page=2":                        false,
+		"/domains/example.com/records/../../example.org/records":     false,
+		"https://api.example.org/domains/example.com/records?page=2": false,
+		"//api.example.org/domains/example.com/records?page=2":       false,
+	}
+
+	for page, permitted := range inputs {
+
+		// act
+		_, err := client.GetRecordsPage(context.Background(), "example.com", page)
+
+		// assert
+		if errors.Is(err, ErrDomainNotPermitted) == permitted {
+			t.Fail()
+			t.Logf("GetRecordsPage(%q) returned %v", page, err)
+		}
+	}
+}
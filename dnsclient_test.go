@@ -5,6 +5,7 @@
 package deens
 
 import (
+	"context"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 )
@@ -50,3 +51,36 @@ func (clientFactory testDNSClientFactory) CreateClient() (DNSClient, error) {
 
 	return clientFactory.client, nil
 }
+
+// testContextDNSClient is a DNS client with native context support.
+// Context functions that are not set fall back to the functions of the
+// embedded testDNSClient.
+type testContextDNSClient struct {
+	testDNSClient
+
+	updateRecordContextFunc func(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error)
+}
+
+func (client *testContextDNSClient) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	if client.updateRecordContextFunc == nil {
+		return client.UpdateRecord(domain, id, opts)
+	}
+
+	return client.updateRecordContextFunc(ctx, domain, id, opts)
+}
+
+func (client *testContextDNSClient) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return client.GetRecords(domain)
+}
+
+func (client *testContextDNSClient) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	return client.GetDomains()
+}
+
+func (client *testContextDNSClient) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.CreateRecord(domain, opts)
+}
+
+func (client *testContextDNSClient) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	return client.DestroyRecord(domain, id)
+}
@@ -0,0 +1,344 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newV1Client creates a DNS client that uses the given DNSimple client
+// for authentication and configuration and sends all requests with the
+// context of the caller and the given user agent.
+func newV1Client(client *dnsimple.Client, userAgent string) *v1Client {
+	return &v1Client{client: client, userAgent: userAgent}
+}
+
+// v1Client implements the DNSClient, the ContextDNSClient, the
+// RecordPager and the RateLimitReporter interface for the DNSimple API v1.
+type v1Client struct {
+	rateLimitRecorder
+
+	client    *dnsimple.Client
+	userAgent string
+}
+
+// UpdateRecord update the DNS record with the given id.
+func (client *v1Client) UpdateRecord(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.UpdateRecordContext(context.Background(), domain, id, opts)
+}
+
+// GetRecords returns all DNS records for the given domain.
+func (client *v1Client) GetRecords(domain string) ([]dnsimple.Record, error) {
+	return client.GetRecordsContext(context.Background(), domain)
+}
+
+// GetDomains returns a list of domain.
+func (client *v1Client) GetDomains() ([]dnsimple.Domain, error) {
+	return client.GetDomainsContext(context.Background())
+}
+
+// CreateRecord creates a new DNS record for the given domain.
+func (client *v1Client) CreateRecord(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.CreateRecordContext(context.Background(), domain, opts)
+}
+
+// DestroyRecord deletes the DNS record with the given id.
+func (client *v1Client) DestroyRecord(domain string, id string) error {
+	return client.DestroyRecordContext(context.Background(), domain, id)
+}
+
+// UpdateRecordContext update the DNS record with the given id.
+func (client *v1Client) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.updateRecord(ctx, domain, id, opts, nil)
+}
+
+// UpdatePriorityRecordContext updates the DNS record with the given id
+// and sets its priority.
+func (client *v1Client) UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.updateRecord(ctx, domain, id, opts, &priority)
+}
+
+// updateRecord updates the DNS record with the given id. The priority
+// is only changed if it is not nil.
+func (client *v1Client) updateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority *int) (string, error) {
+	params := make(map[string]interface{})
+
+	if opts.Name != "" {
+		params["name"] = opts.Name
+	}
+
+	if opts.Type != "" {
+		params["record_type"] = opts.Type
+	}
+
+	if opts.Value != "" {
+		params["content"] = opts.Value
+	}
+
+	if err := setTTLParameter(params, opts.Ttl); err != nil {
+		return "", err
+	}
+
+	if priority != nil {
+		params["prio"] = *priority
+	}
+
+	var record dnsimple.RecordResponse
+	endpoint := fmt.Sprintf("/domains/%s/records/%s", domain, id)
+	if err := client.do(ctx, "PUT", endpoint, params, &record); err != nil {
+		return "", fmt.Errorf("Error updating record: %w", annotateAPIError(err, domain, id))
+	}
+
+	return record.Record.StringId(), nil
+}
+
+// GetRecordsContext returns all DNS records for the given domain.
+// All pages of the response are read.
+func (client *v1Client) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return collectRecords(ctx, client, domain)
+}
+
+// GetRecordsPage returns the given page of DNS records for the given
+// domain. The page is taken from the next page link of the API.
+func (client *v1Client) GetRecordsPage(ctx context.Context, domain, page string) (RecordPage, error) {
+	endpoint := "/domains/" + domain + "/records"
+	if page != "" {
+		endpoint = page
+	}
+
+	var recordResponses []dnsimple.RecordResponse
+	next, err := client.getPage(ctx, endpoint, &recordResponses)
+	if err != nil {
+		return RecordPage{}, annotateAPIError(err, domain, "")
+	}
+
+	records := make([]dnsimple.Record, len(recordResponses))
+	for index, recordResponse := range recordResponses {
+		records[index] = recordResponse.Record
+	}
+
+	return RecordPage{records, next}, nil
+}
+
+// GetDomainsContext returns a list of domain.
+// All pages of the response are read.
+func (client *v1Client) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	var domains []dnsimple.Domain
+	visited := make(map[string]bool)
+	endpoint := "/domains"
+	for endpoint != "" {
+		visited[endpoint] = true
+		var domainResponses []dnsimple.DomainResponse
+		next, err := client.getPage(ctx, endpoint, &domainResponses)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, domainResponse := range domainResponses {
+			domains = append(domains, domainResponse.Domain)
+		}
+
+		if visited[next] {
+			return nil, fmt.Errorf("The API returned the page %q twice", next)
+		}
+
+		endpoint = next
+	}
+
+	return domains, nil
+}
+
+// CreateRecordContext creates a new DNS record for the given domain.
+func (client *v1Client) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.createRecord(ctx, domain, opts, nil)
+}
+
+// CreatePriorityRecordContext creates a new DNS record with the given
+// priority for the given domain.
+func (client *v1Client) CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.createRecord(ctx, domain, opts, &priority)
+}
+
+// createRecord creates a new DNS record for the given domain. The
+// priority is only sent if it is not nil.
+func (client *v1Client) createRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority *int) (string, error) {
+	params := make(map[string]interface{})
+	params["name"] = opts.Name
+	params["record_type"] = opts.Type
+	params["content"] = opts.Value
+
+	if err := setTTLParameter(params, opts.Ttl); err != nil {
+		return "", err
+	}
+
+	if priority != nil {
+		params["prio"] = *priority
+	}
+
+	var record dnsimple.RecordResponse
+	if err := client.do(ctx, "POST", fmt.Sprintf("/domains/%s/records", domain), params, &record); err != nil {
+		return "", fmt.Errorf("Error creating record: %w", annotateAPIError(err, domain, ""))
+	}
+
+	return record.Record.StringId(), nil
+}
+
+// DestroyRecordContext deletes the DNS record with the given id.
+func (client *v1Client) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	if err := client.do(ctx, "DELETE", fmt.Sprintf("/domains/%s/records/%s", domain, id), nil, nil); err != nil {
+		return fmt.Errorf("Error destroying record: %w", annotateAPIError(err, domain, id))
+	}
+
+	return nil
+}
+
+// do sends a request with the given method, endpoint and parameters and
+// decodes the JSON response into the given result (if not nil).
+func (client *v1Client) do(ctx context.Context, method, endpoint string, params map[string]interface{}, result interface{}) error {
+	response, body, err := client.send(ctx, method, endpoint, params)
+	if err != nil {
+		return err
+	}
+
+	if err := checkResponse(response, body); err != nil {
+		return err
+	}
+
+	if result == nil || len(body) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("Error parsing response: %s", err.Error())
+	}
+
+	return nil
+}
+
+// getPage requests the given endpoint, decodes the JSON response into
+// the given result and returns the endpoint of the next page. The
+// endpoint is empty if there is no next page.
+func (client *v1Client) getPage(ctx context.Context, endpoint string, result interface{}) (string, error) {
+	response, body, err := client.send(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkResponse(response, body); err != nil {
+		return "", err
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return "", fmt.Errorf("Error parsing response: %s", err.Error())
+	}
+
+	return client.nextPageEndpoint(response.Header)
+}
+
+// nextPageEndpoint returns the endpoint of the next page link in the
+// given response headers relative to the base URL.
+func (client *v1Client) nextPageEndpoint(header http.Header) (string, error) {
+	link := parseNextPageLink(header.Get("Link"))
+	if link == "" {
+		return "", nil
+	}
+
+	baseURL, err := url.Parse(client.client.URL)
+	if err != nil {
+		return "", err
+	}
+
+	nextURL, err := baseURL.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("Invalid next page link %q: %s", link, err.Error())
+	}
+
+	if !strings.HasPrefix(nextURL.String(), client.client.URL+"/") {
+		return "", fmt.Errorf("The next page link %q does not belong to the API", link)
+	}
+
+	return strings.TrimPrefix(nextURL.String(), client.client.URL), nil
+}
+
+// send sends a request with the given method, endpoint and parameters
+// and returns the response together with its body.
+func (client *v1Client) send(ctx context.Context, method, endpoint string, params map[string]interface{}) (*http.Response, []byte, error) {
+	request, err := client.client.NewRequest(params, method, endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if client.userAgent != "" {
+		request.Header.Set("User-Agent", client.userAgent)
+	}
+
+	response, err := client.client.Http.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer response.Body.Close()
+	client.record(response.Header)
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, body, nil
+}
+
+// checkResponse returns an APIError if the given response does not
+// indicate success. The message and the validation errors contained in
+// the body of the response are included in the error.
+func checkResponse(response *http.Response, body []byte) error {
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	}
+
+	apiError := &APIError{
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+	}
+
+	var errorResponse struct {
+		dnsimple.DNSimpleError
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(body, &errorResponse); err == nil {
+		apiError.Message = errorResponse.Message
+		apiError.Errors = errorResponse.Errors
+	}
+
+	return apiError
+}
+
+// setTTLParameter adds the given TTL to the given request parameters
+// if it is not empty.
+func setTTLParameter(params map[string]interface{}, ttl string) error {
+	if ttl == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseInt(ttl, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Invalid TTL %q: %s", ttl, err.Error())
+	}
+
+	params["ttl"] = value
+	return nil
+}
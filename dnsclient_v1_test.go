@@ -0,0 +1,172 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestV1Client creates a v1 client that sends all requests to the given test server.
+func newTestV1Client(server *httptest.Server) *v1Client {
+	return newTestV1ClientForCredentials(APICredentials{"john.doe@example.com", "ApItOken"}, server)
+}
+
+// GetRecords should return the records from the records endpoint of the given domain.
+func Test_v1Client_GetRecords_RecordsAreReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/domains/example.com/records" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprint(w, `[{"record": {"id": 1, "name": "www", "record_type": "A", "content": "127.0.0.1", "ttl": 600}}]`)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	records, err := client.GetRecords("example.com")
+
+	// assert
+	if err != nil || len(records) != 1 || records[0].Name != "www" || records[0].Ttl != 600 {
+		t.Fail()
+		t.Logf("GetRecords() returned %v, %v", records, err)
+	}
+}
+
+// CreateRecord should post the record parameters and return the ID of the new record.
+func Test_v1Client_CreateRecord_IDIsReturned(t *testing.T) {
+	// arrange
+	var params map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&params)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"record": {"id": 42, "name": "www", "record_type": "A", "content": "127.0.0.1", "ttl": 600}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	id, err := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1", Ttl: "600"})
+
+	// assert
+	if err != nil || id != "42" {
+		t.Fail()
+		t.Logf("CreateRecord() should return the ID 42 but returned %q, %v", id, err)
+	}
+
+	if params["name"] != "www" || params["record_type"] != "A" || params["content"] != "127.0.0.1" || params["ttl"] != float64(600) {
+		t.Fail()
+		t.Logf("CreateRecord() sent unexpected parameters: %v", params)
+	}
+}
+
+// CreatePriorityRecordContext should send the priority as prio parameter.
+func Test_v1Client_CreatePriorityRecord_PrioIsSent(t *testing.T) {
+	// arrange
+	var params map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&params)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"record": {"id": 42, "name": "", "record_type": "MX", "content": "mx.example.com", "ttl": 3600, "prio": 10}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	_, err := client.CreatePriorityRecordContext(context.Background(), "example.com", &dnsimple.ChangeRecord{Type: "MX", Value: "mx.example.com"}, 10)
+
+	// assert
+	if err != nil || params["prio"] != float64(10) {
+		t.Fail()
+		t.Logf("CreatePriorityRecordContext() should send the prio 10 but sent %v (%v)", params, err)
+	}
+}
+
+// Errors responses should be returned as errors.
+func Test_v1Client_APIRespondsWithError_ErrorIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	_, err := client.GetDomains()
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("GetDomains() should return an error if the API responds with 401")
+	}
+}
+
+// Requests should be aborted when the context expires.
+func Test_v1Client_ContextDeadlineExceeded_RequestIsAborted(t *testing.T) {
+	// arrange
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestV1Client(server)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// act
+	start := time.Now()
+	_, err := client.GetRecordsContext(ctx, "example.com")
+
+	// assert
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fail()
+		t.Logf("GetRecordsContext() should return context.DeadlineExceeded but returned %v", err)
+	}
+
+	if time.Since(start) > 2*time.Second {
+		t.Fail()
+		t.Logf("GetRecordsContext() should return as soon as the context expires")
+	}
+}
+
+// The adapter should not call the wrapped client if the context is already cancelled.
+func Test_AsContextDNSClient_ContextCancelled_ClientIsNotCalled(t *testing.T) {
+	// arrange
+	called := false
+	client := AsContextDNSClient(&testDNSClient{
+		getDomainsFunc: func() ([]dnsimple.Domain, error) {
+			called = true
+			return nil, nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// act
+	_, err := client.GetDomainsContext(ctx)
+
+	// assert
+	if !errors.Is(err, context.Canceled) || called {
+		t.Fail()
+		t.Logf("GetDomainsContext() should return context.Canceled without calling the client but returned %v (called: %t)", err, called)
+	}
+}
@@ -0,0 +1,528 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// newV2ClientForCredentials creates a DNSimple API v2 client for the
+// given credentials and options.
+func newV2ClientForCredentials(credentials AccessTokenCredentials, options ...ClientOption) (*v2Client, error) {
+	if isEmpty(credentials.Token) {
+		return nil, fmt.Errorf("No access token given")
+	}
+
+	settings, err := newClientOptions(DefaultV2BaseURL, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2Client{
+		httpClient: settings.newHTTPClient(),
+		baseURL:    settings.baseURL,
+		userAgent:  settings.userAgent,
+		token:      credentials.Token,
+		accountID:  credentials.AccountID,
+	}, nil
+}
+
+// v2Client implements the DNSClient, the ContextDNSClient, the
+// RecordPager, the RecordFilterer and the RateLimitReporter interface
+// for the DNSimple API v2.
+type v2Client struct {
+	rateLimitRecorder
+
+	httpClient *http.Client
+	baseURL    string
+	userAgent  string
+	token      string
+
+	// accountID is resolved with the whoami endpoint if it is not
+	// part of the credentials
+	accountLock sync.Mutex
+	accountID   string
+}
+
+// v2Record is a zone record of the DNSimple API v2.
+type v2Record struct {
+	ID       int64  `json:"id"`
+	ZoneID   string `json:"zone_id"`
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	TTL      int64  `json:"ttl"`
+	Priority int64  `json:"priority"`
+	Type     string `json:"type"`
+}
+
+// toRecord converts the v2 record to the record model used by DNSClient.
+func (record v2Record) toRecord() dnsimple.Record {
+	return dnsimple.Record{
+		Id:         record.ID,
+		Name:       record.Name,
+		Content:    record.Content,
+		Ttl:        record.TTL,
+		Prio:       record.Priority,
+		RecordType: record.Type,
+	}
+}
+
+// v2Domain is a domain of the DNSimple API v2.
+type v2Domain struct {
+	ID          int       `json:"id"`
+	AccountID   int       `json:"account_id"`
+	Name        string    `json:"name"`
+	UnicodeName string    `json:"unicode_name"`
+	State       string    `json:"state"`
+	AutoRenew   bool      `json:"auto_renew"`
+	ExpiresOn   string    `json:"expires_on"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// toDomain converts the v2 domain to the domain model used by
+// DNSClient. The account ID is returned as the user ID.
+func (domain v2Domain) toDomain() dnsimple.Domain {
+	return dnsimple.Domain{
+		Id:          domain.ID,
+		UserId:      domain.AccountID,
+		Name:        domain.Name,
+		UnicodeName: domain.UnicodeName,
+		State:       domain.State,
+		AutoRenew:   domain.AutoRenew,
+		ExpiresOn:   domain.ExpiresOn,
+		CreatedAt:   domain.CreatedAt,
+		UpdatedAt:   domain.UpdatedAt,
+	}
+}
+
+// v2Pagination describes the page of a list response of the DNSimple API v2.
+type v2Pagination struct {
+	CurrentPage  int `json:"current_page"`
+	PerPage      int `json:"per_page"`
+	TotalEntries int `json:"total_entries"`
+	TotalPages   int `json:"total_pages"`
+}
+
+// v2PageSize is the number of entries that are requested per page
+// (the maximum allowed by the API).
+const v2PageSize = 100
+
+// v2Whoami is the response of the whoami endpoint of the DNSimple API v2.
+type v2Whoami struct {
+	Account *struct {
+		ID    int    `json:"id"`
+		Email string `json:"email"`
+	} `json:"account"`
+	User *struct {
+		ID    int    `json:"id"`
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+// UpdateRecord update the DNS record with the given id.
+func (client *v2Client) UpdateRecord(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.UpdateRecordContext(context.Background(), domain, id, opts)
+}
+
+// GetRecords returns all DNS records for the given domain.
+func (client *v2Client) GetRecords(domain string) ([]dnsimple.Record, error) {
+	return client.GetRecordsContext(context.Background(), domain)
+}
+
+// GetDomains returns a list of domain.
+func (client *v2Client) GetDomains() ([]dnsimple.Domain, error) {
+	return client.GetDomainsContext(context.Background())
+}
+
+// CreateRecord creates a new DNS record for the given domain.
+func (client *v2Client) CreateRecord(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.CreateRecordContext(context.Background(), domain, opts)
+}
+
+// DestroyRecord deletes the DNS record with the given id.
+func (client *v2Client) DestroyRecord(domain string, id string) error {
+	return client.DestroyRecordContext(context.Background(), domain, id)
+}
+
+// UpdateRecordContext update the DNS record with the given id.
+// The API v2 does not allow changing the type of a record; the type
+// of the change record is ignored.
+func (client *v2Client) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.updateRecord(ctx, domain, id, opts, nil)
+}
+
+// UpdatePriorityRecordContext updates the DNS record with the given id
+// and sets its priority.
+func (client *v2Client) UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.updateRecord(ctx, domain, id, opts, &priority)
+}
+
+// updateRecord updates the DNS record with the given id. The priority
+// is only changed if it is not nil.
+func (client *v2Client) updateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority *int) (string, error) {
+	params := make(map[string]interface{})
+
+	if opts.Name != "" {
+		params["name"] = opts.Name
+	}
+
+	if opts.Value != "" {
+		params["content"] = opts.Value
+	}
+
+	if err := setTTLParameter(params, opts.Ttl); err != nil {
+		return "", err
+	}
+
+	if priority != nil {
+		params["priority"] = *priority
+	}
+
+	var record v2Record
+	if err := client.doAccount(ctx, "PATCH", client.zonePath(domain, "records", id), params, &record); err != nil {
+		return "", fmt.Errorf("Error updating record: %w", annotateAPIError(err, domain, id))
+	}
+
+	return strconv.FormatInt(record.ID, 10), nil
+}
+
+// GetRecordsContext returns all DNS records for the given domain.
+// All pages of the response are read.
+func (client *v2Client) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return collectRecords(ctx, client, domain)
+}
+
+// GetRecordsPage returns the given page of DNS records for the given
+// domain. The page is the page number; the first page is used if it
+// is empty.
+func (client *v2Client) GetRecordsPage(ctx context.Context, domain, page string) (RecordPage, error) {
+	var recordResponses []v2Record
+	next, err := client.getAccountPage(ctx, client.zonePath(domain, "records"), nil, page, &recordResponses)
+	if err != nil {
+		return RecordPage{}, annotateAPIError(err, domain, "")
+	}
+
+	records := make([]dnsimple.Record, len(recordResponses))
+	for index, recordResponse := range recordResponses {
+		records[index] = recordResponse.toRecord()
+	}
+
+	return RecordPage{records, next}, nil
+}
+
+// GetFilteredRecordsContext returns the DNS records of the given domain
+// that match the given filter. The name and the type are filtered by
+// the API. The empty name (the records of the domain itself) is not
+// sent, so these records are filtered locally.
+func (client *v2Client) GetFilteredRecordsContext(ctx context.Context, domain string, filter RecordFilter) ([]dnsimple.Record, error) {
+	query := url.Values{}
+	if filter.Name != "" {
+		query.Set("name", filter.Name)
+	}
+
+	if filter.Type != "" {
+		query.Set("type", filter.Type)
+	}
+
+	var records []dnsimple.Record
+	page := ""
+	for {
+		var recordResponses []v2Record
+		next, err := client.getAccountPage(ctx, client.zonePath(domain, "records"), query, page, &recordResponses)
+		if err != nil {
+			return nil, annotateAPIError(err, domain, "")
+		}
+
+		for _, recordResponse := range recordResponses {
+			records = append(records, recordResponse.toRecord())
+		}
+
+		if next == "" {
+			// the filter is applied again in case the API ignores a parameter
+			return filter.apply(records), nil
+		}
+
+		if err := checkNextPage(page, next); err != nil {
+			return nil, err
+		}
+
+		page = next
+	}
+}
+
+// GetDomainsContext returns a list of domain.
+// All pages of the response are read.
+func (client *v2Client) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	var domains []dnsimple.Domain
+	page := ""
+	for {
+		var domainResponses []v2Domain
+		next, err := client.getAccountPage(ctx, "/domains", nil, page, &domainResponses)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, domainResponse := range domainResponses {
+			domains = append(domains, domainResponse.toDomain())
+		}
+
+		if next == "" {
+			return domains, nil
+		}
+
+		if err := checkNextPage(page, next); err != nil {
+			return nil, err
+		}
+
+		page = next
+	}
+}
+
+// CreateRecordContext creates a new DNS record for the given domain.
+func (client *v2Client) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return client.createRecord(ctx, domain, opts, nil)
+}
+
+// CreatePriorityRecordContext creates a new DNS record with the given
+// priority for the given domain.
+func (client *v2Client) CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.createRecord(ctx, domain, opts, &priority)
+}
+
+// createRecord creates a new DNS record for the given domain. The
+// priority is only sent if it is not nil.
+func (client *v2Client) createRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority *int) (string, error) {
+	params := make(map[string]interface{})
+	params["name"] = opts.Name
+	params["type"] = opts.Type
+	params["content"] = opts.Value
+
+	if err := setTTLParameter(params, opts.Ttl); err != nil {
+		return "", err
+	}
+
+	if priority != nil {
+		params["priority"] = *priority
+	}
+
+	var record v2Record
+	if err := client.doAccount(ctx, "POST", client.zonePath(domain, "records"), params, &record); err != nil {
+		return "", fmt.Errorf("Error creating record: %w", annotateAPIError(err, domain, ""))
+	}
+
+	return strconv.FormatInt(record.ID, 10), nil
+}
+
+// DestroyRecordContext deletes the DNS record with the given id.
+func (client *v2Client) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	if err := client.doAccount(ctx, "DELETE", client.zonePath(domain, "records", id), nil, nil); err != nil {
+		return fmt.Errorf("Error destroying record: %w", annotateAPIError(err, domain, id))
+	}
+
+	return nil
+}
+
+// whoami returns the account and the user the access token belongs to.
+func (client *v2Client) whoami(ctx context.Context) (v2Whoami, error) {
+	var whoami v2Whoami
+	err := client.do(ctx, "GET", "/whoami", nil, &whoami)
+	return whoami, err
+}
+
+// account returns the ID of the account. If it is not part of the
+// credentials it is resolved with the whoami endpoint once.
+func (client *v2Client) account(ctx context.Context) (string, error) {
+	client.accountLock.Lock()
+	defer client.accountLock.Unlock()
+
+	if client.accountID != "" {
+		return client.accountID, nil
+	}
+
+	whoami, err := client.whoami(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if whoami.Account == nil {
+		return "", fmt.Errorf("The access token belongs to a user; an account ID is required")
+	}
+
+	client.accountID = strconv.Itoa(whoami.Account.ID)
+	return client.accountID, nil
+}
+
+// zonePath returns the path of the given zone and the given sub
+// resources relative to the account.
+func (client *v2Client) zonePath(zone string, elements ...string) string {
+	path := "/zones/" + url.PathEscape(zone)
+	for _, element := range elements {
+		path += "/" + url.PathEscape(element)
+	}
+
+	return path
+}
+
+// doAccount sends a request to the given path of the account.
+func (client *v2Client) doAccount(ctx context.Context, method, path string, params map[string]interface{}, result interface{}) error {
+	accountID, err := client.account(ctx)
+	if err != nil {
+		return err
+	}
+
+	return client.do(ctx, method, "/"+url.PathEscape(accountID)+path, params, result)
+}
+
+// getAccountPage requests the given page of the given list path of the
+// account with the given query parameters, decodes the data into the
+// given result and returns the number of the next page. The next page
+// is empty on the last page.
+func (client *v2Client) getAccountPage(ctx context.Context, path string, query url.Values, page string, result interface{}) (string, error) {
+	pageNumber := 1
+	if page != "" {
+		number, err := strconv.Atoi(page)
+		if err != nil || number < 1 {
+			return "", fmt.Errorf("Invalid page %q", page)
+		}
+
+		pageNumber = number
+	}
+
+	accountID, err := client.account(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	parameters := url.Values{}
+	for name, values := range query {
+		parameters[name] = values
+	}
+
+	parameters.Set("page", strconv.Itoa(pageNumber))
+	parameters.Set("per_page", strconv.Itoa(v2PageSize))
+
+	endpoint := "/" + url.PathEscape(accountID) + path + "?" + parameters.Encode()
+	pagination, err := client.request(ctx, "GET", endpoint, nil, result)
+	if err != nil {
+		return "", err
+	}
+
+	if pagination == nil || pagination.CurrentPage >= pagination.TotalPages {
+		return "", nil
+	}
+
+	return strconv.Itoa(pagination.CurrentPage + 1), nil
+}
+
+// checkNextPage returns an error if the given next page does not come
+// after the given page (e.g. because the API returned a page twice).
+func checkNextPage(page, next string) error {
+	pageNumber := 1
+	if page != "" {
+		pageNumber, _ = strconv.Atoi(page)
+	}
+
+	if nextNumber, err := strconv.Atoi(next); err != nil || nextNumber <= pageNumber {
+		return fmt.Errorf("The API returned the page %q after the page %q", next, page)
+	}
+
+	return nil
+}
+
+// do sends a request with the given method, endpoint and parameters and
+// decodes the data of the JSON response into the given result (if not nil).
+func (client *v2Client) do(ctx context.Context, method, endpoint string, params map[string]interface{}, result interface{}) error {
+	_, err := client.request(ctx, method, endpoint, params, result)
+	return err
+}
+
+// request sends a request with the given method, endpoint and parameters,
+// decodes the data of the JSON response into the given result (if not
+// nil) and returns the pagination of the response (if any).
+func (client *v2Client) request(ctx context.Context, method, endpoint string, params map[string]interface{}, result interface{}) (*v2Pagination, error) {
+	response, body, err := client.send(ctx, method, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(response, body); err != nil {
+		return nil, err
+	}
+
+	if result == nil || len(body) == 0 {
+		return nil, nil
+	}
+
+	var envelope struct {
+		Data       json.RawMessage `json:"data"`
+		Pagination *v2Pagination   `json:"pagination"`
+	}
+
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("Error parsing response: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(envelope.Data, result); err != nil {
+		return nil, fmt.Errorf("Error parsing response: %s", err.Error())
+	}
+
+	return envelope.Pagination, nil
+}
+
+// send sends a request with the given method, endpoint and parameters
+// and returns the response together with its body.
+func (client *v2Client) send(ctx context.Context, method, endpoint string, params map[string]interface{}) (*http.Response, []byte, error) {
+	var requestBody io.Reader
+	if params != nil {
+		content, err := json.Marshal(params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error encoding request body: %s", err.Error())
+		}
+
+		requestBody = bytes.NewReader(content)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, client.baseURL+endpoint, requestBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error creating request: %s", err.Error())
+	}
+
+	request.Header.Set("Authorization", "Bearer "+client.token)
+	request.Header.Set("Accept", "application/json")
+	if requestBody != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if client.userAgent != "" {
+		request.Header.Set("User-Agent", client.userAgent)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer response.Body.Close()
+	client.record(response.Header)
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, body, nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestV2Client creates a v2 client for the given account that sends
+// all requests to the given test server.
+func newTestV2Client(accountID string, server *httptest.Server) *v2Client {
+	client, _ := newV2ClientForCredentials(AccessTokenCredentials{accountID, "AcCeSsToKeN"}, WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	return client
+}
+
+// GetRecords should return the records of the zone endpoint and send the bearer token.
+func Test_v2Client_GetRecords_RecordsAreReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/1010/zones/example.com/records" || r.Header.Get("Authorization") != "Bearer AcCeSsToKeN" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprint(w, `{"data": [{"id": 1, "zone_id": "example.com", "name": "www", "type": "A", "content": "127.0.0.1", "ttl": 600, "priority": null}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	records, err := client.GetRecords("example.com")
+
+	// assert
+	if err != nil || len(records) != 1 || records[0].Name != "www" || records[0].RecordType != "A" || records[0].Ttl != 600 {
+		t.Fail()
+		t.Logf("GetRecords() returned %v, %v", records, err)
+	}
+}
+
+// CreateRecord should post the record parameters and return the ID of the new record.
+func Test_v2Client_CreateRecord_IDIsReturned(t *testing.T) {
+	// arrange
+	var params map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/1010/zones/example.com/records" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&params)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"data": {"id": 42, "zone_id": "example.com", "name": "www", "type": "A", "content": "127.0.0.1", "ttl": 600}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	id, err := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1", Ttl: "600"})
+
+	// assert
+	if err != nil || id != "42" {
+		t.Fatalf("CreateRecord() should return the ID 42 but returned %q, %v", id, err)
+	}
+
+	if params["name"] != "www" || params["type"] != "A" || params["content"] != "127.0.0.1" || params["ttl"] != float64(600) {
+		t.Fail()
+		t.Logf("CreateRecord() sent unexpected parameters: %v", params)
+	}
+}
+
+// UpdatePriorityRecordContext should send the priority parameter.
+func Test_v2Client_UpdatePriorityRecord_PriorityIsSent(t *testing.T) {
+	// arrange
+	var params map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&params)
+		fmt.Fprint(w, `{"data": {"id": 42, "zone_id": "example.com", "name": "", "type": "MX", "content": "mx.example.com", "ttl": 3600, "priority": 20}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	_, err := client.UpdatePriorityRecordContext(context.Background(), "example.com", "42", &dnsimple.ChangeRecord{Value: "mx.example.com"}, 20)
+
+	// assert
+	if err != nil || params["priority"] != float64(20) {
+		t.Fail()
+		t.Logf("UpdatePriorityRecordContext() should send the priority 20 but sent %v (%v)", params, err)
+	}
+}
+
+// UpdateRecord should patch the record and must not send the record type.
+func Test_v2Client_UpdateRecord_RecordIsPatched(t *testing.T) {
+	// arrange
+	var params map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/1010/zones/example.com/records/42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&params)
+		fmt.Fprint(w, `{"data": {"id": 42, "zone_id": "example.com", "name": "www", "type": "A", "content": "127.0.0.1", "ttl": 600}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	id, err := client.UpdateRecord("example.com", "42", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1", Ttl: "600"})
+
+	// assert
+	if err != nil || id != "42" {
+		t.Fatalf("UpdateRecord() should return the ID 42 but returned %q, %v", id, err)
+	}
+
+	if _, ok := params["type"]; ok || params["content"] != "127.0.0.1" {
+		t.Fail()
+		t.Logf("UpdateRecord() sent unexpected parameters: %v", params)
+	}
+}
+
+// DestroyRecord should delete the record and return a typed error if it does not exist.
+func Test_v2Client_DestroyRecord_RecordNotFound_NotFoundErrorIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/1010/zones/example.com/records/42" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Record '42' not found"}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	err := client.DestroyRecord("example.com", "42")
+
+	// assert
+	var apiError *APIError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &apiError) || apiError.RecordID != "42" || apiError.Message != "Record '42' not found" {
+		t.Fail()
+		t.Logf("DestroyRecord() should return a not found error for record 42 but returned %v", err)
+	}
+}
+
+// Without an account ID the account should be resolved with the whoami endpoint once.
+func Test_v2Client_NoAccountID_AccountIsResolvedOnce(t *testing.T) {
+	// arrange
+	whoamiRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/whoami":
+			whoamiRequests++
+			fmt.Fprint(w, `{"data": {"user": null, "account": {"id": 1010, "email": "john.doe@example.com"}}}`)
+		case "/1010/domains":
+			fmt.Fprint(w, `{"data": [{"id": 1, "account_id": 1010, "name": "example.com", "state": "hosted"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("", server)
+
+	// act
+	client.GetDomains()
+	domains, err := client.GetDomains()
+
+	// assert
+	if err != nil || len(domains) != 1 || domains[0].Name != "example.com" || domains[0].UserId != 1010 {
+		t.Fatalf("GetDomains() returned %v, %v", domains, err)
+	}
+
+	if whoamiRequests != 1 {
+		t.Fail()
+		t.Logf("The account should have been resolved once but whoami was called %d times", whoamiRequests)
+	}
+}
+
+// A user token cannot be used without an account ID.
+func Test_v2Client_UserTokenWithoutAccountID_ErrorIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"user": {"id": 1, "email": "john.doe@example.com"}, "account": null}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("", server)
+
+	// act
+	_, err := client.GetRecords("example.com")
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("GetRecords() should return an error if the account cannot be determined")
+	}
+}
+
+// The DNSEditor should work unchanged with a v2 client.
+func Test_NewDNSClient_AccessTokenCredentials_EditorUpdatesRecord(t *testing.T) {
+	// arrange
+	var updatedContent interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/v2/1010/zones/example.com/records":
+			fmt.Fprint(w, `{"data": [{"id": 42, "zone_id": "example.com", "name": "www", "type": "A", "content": "127.0.0.1", "ttl": 600}]}`)
+		case r.Method == "PATCH" && r.URL.Path == "/v2/1010/zones/example.com/records/42":
+			var params map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&params)
+			updatedContent = params["content"]
+			fmt.Fprint(w, `{"data": {"id": 42, "zone_id": "example.com", "name": "www", "type": "A", "content": "127.0.0.2", "ttl": 600}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewDNSClient(AccessTokenCredentials{"1010", "AcCeSsToKeN"}, WithBaseURL(server.URL+"/v2"))
+	if err != nil {
+		t.Fatalf("NewDNSClient() returned an error: %s", err.Error())
+	}
+
+	editor := NewDNSEditor(client, NewDNSInfoProvider(client))
+
+	// act
+	err = editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.2"))
+
+	// assert
+	if err != nil || updatedContent != "127.0.0.2" {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should update the record via the API v2 but returned %v (content: %v)", err, updatedContent)
+	}
+}
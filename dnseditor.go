@@ -5,16 +5,24 @@
 package deens
 
 import (
+	"context"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 	"net"
 )
 
+// MinTTL is the smallest time to live (in seconds) that is accepted for new records.
+const MinTTL = 60
+
+// MaxTTL is the largest time to live (in seconds) that is accepted for new
+// records (see RFC 2181).
+const MaxTTL = 2147483647
+
 // The DNSRecordCreator interface offers functions for creating domain records.
 type DNSRecordCreator interface {
 
-	// CreateSubdomain creates a new subdomain address record.
-	CreateSubdomain(domain, subDomainName string, timeToLive int, ip net.IP) error
+	// CreateSubdomain creates a new subdomain address record and returns its ID.
+	CreateSubdomain(domain, subDomainName string, timeToLive int, ip net.IP) (string, error)
 }
 
 // The DNSRecordUpdater interface offers functions for updating domain records.
@@ -24,6 +32,33 @@ type DNSRecordUpdater interface {
 	UpdateSubdomain(domain, subDomainName string, ip net.IP) error
 }
 
+// The DNSRecordTTLUpdater interface offers functions for changing the
+// time to live of domain records. It is implemented by *DNSEditor.
+type DNSRecordTTLUpdater interface {
+
+	// UpdateSubdomainWithTTL sets ip address and time to live of the given subdomain.
+	UpdateSubdomainWithTTL(domain, subDomainName string, timeToLive int, ip net.IP) error
+
+	// UpdateSubdomainTTL sets the time to live of the given subdomain address record.
+	UpdateSubdomainTTL(domain, subDomainName string, recordType string, timeToLive int) error
+}
+
+// The DNSRecordUpserter interface offers functions for setting domain
+// records regardless of whether they exist.
+type DNSRecordUpserter interface {
+
+	// UpsertSubdomain creates or updates the subdomain address record.
+	UpsertSubdomain(domain, subDomainName string, timeToLive int, ip net.IP) (UpsertResult, error)
+}
+
+// The DNSDualStackUpdater interface offers functions for setting the A and
+// the AAAA record of a subdomain at once. It is implemented by *DNSEditor.
+type DNSDualStackUpdater interface {
+
+	// UpdateDualStack creates or updates the A and the AAAA record of the subdomain.
+	UpdateDualStack(domain, subDomainName string, update DualStackUpdate) (DualStackResult, error)
+}
+
 // The DNSRecordDeleter interface offers functions for creating domain records.
 type DNSRecordDeleter interface {
 
@@ -31,14 +66,136 @@ type DNSRecordDeleter interface {
 	DeleteSubdomain(domain, subDomainName string, recordType string) error
 }
 
+// The DNSRecordSetEditor interface offers functions for editing all
+// address records of one name and type as a set (e.g. for round-robin setups).
+// It is implemented by *DNSEditor.
+type DNSRecordSetEditor interface {
+
+	// ReplaceRecordSet makes the address record set point to exactly the given IP addresses.
+	ReplaceRecordSet(domain, subDomainName, recordType string, timeToLive int, ips []net.IP) (RecordSetChange, error)
+
+	// AddToRecordSet adds the given IP address to the address record set.
+	AddToRecordSet(domain, subDomainName string, timeToLive int, ip net.IP) (RecordSetChange, error)
+
+	// RemoveFromRecordSet removes the given IP address from the address record set.
+	RemoveFromRecordSet(domain, subDomainName string, ip net.IP) (RecordSetChange, error)
+}
+
+// The DNSTypedRecordEditor interface offers functions for editing records
+// of other types than A and AAAA (see TypedRecord). It is implemented by
+// *DNSEditor.
+type DNSTypedRecordEditor interface {
+
+	// CreateRecord creates the given record and returns its ID.
+	CreateRecord(domain, name string, timeToLive int, record TypedRecord) (string, error)
+
+	// UpdateRecord replaces the given current record with the updated one.
+	UpdateRecord(domain, name string, timeToLive int, current, updated TypedRecord) error
+
+	// DeleteRecord removes the given record.
+	DeleteRecord(domain, name string, record TypedRecord) error
+}
+
 // The DNSRecordEditor interface provides functions for editing DNS records.
 type DNSRecordEditor interface {
 	DNSRecordCreator
 	DNSRecordUpdater
+	DNSRecordUpserter
 	DNSRecordDeleter
 }
 
+// The ContextDNSRecordCreator interface offers functions for creating domain
+// records that can be cancelled with a context.
+type ContextDNSRecordCreator interface {
+
+	// CreateSubdomainContext creates a new subdomain address record and returns its ID.
+	CreateSubdomainContext(ctx context.Context, domain, subDomainName string, timeToLive int, ip net.IP) (string, error)
+}
+
+// The ContextDNSRecordUpdater interface offers functions for updating domain
+// records that can be cancelled with a context.
+type ContextDNSRecordUpdater interface {
+
+	// UpdateSubdomainContext sets ip address of the given subdomain.
+	UpdateSubdomainContext(ctx context.Context, domain, subDomainName string, ip net.IP) error
+}
+
+// The ContextDNSRecordTTLUpdater interface offers functions for changing the
+// time to live of domain records that can be cancelled with a context.
+type ContextDNSRecordTTLUpdater interface {
+
+	// UpdateSubdomainWithTTLContext sets ip address and time to live of the given subdomain.
+	UpdateSubdomainWithTTLContext(ctx context.Context, domain, subDomainName string, timeToLive int, ip net.IP) error
+
+	// UpdateSubdomainTTLContext sets the time to live of the given subdomain address record.
+	UpdateSubdomainTTLContext(ctx context.Context, domain, subDomainName string, recordType string, timeToLive int) error
+}
+
+// The ContextDNSRecordUpserter interface offers functions for setting
+// domain records that can be cancelled with a context.
+type ContextDNSRecordUpserter interface {
+
+	// UpsertSubdomainContext creates or updates the subdomain address record.
+	UpsertSubdomainContext(ctx context.Context, domain, subDomainName string, timeToLive int, ip net.IP) (UpsertResult, error)
+}
+
+// The ContextDNSDualStackUpdater interface offers functions for setting the
+// A and the AAAA record of a subdomain at once that can be cancelled with a context.
+type ContextDNSDualStackUpdater interface {
+
+	// UpdateDualStackContext creates or updates the A and the AAAA record of the subdomain.
+	UpdateDualStackContext(ctx context.Context, domain, subDomainName string, update DualStackUpdate) (DualStackResult, error)
+}
+
+// The ContextDNSRecordDeleter interface offers functions for deleting domain
+// records that can be cancelled with a context.
+type ContextDNSRecordDeleter interface {
+
+	// DeleteSubdomainContext removes subdomain address record of the given type.
+	DeleteSubdomainContext(ctx context.Context, domain, subDomainName string, recordType string) error
+}
+
+// The ContextDNSRecordSetEditor interface offers functions for editing all
+// address records of one name and type as a set that can be cancelled with a context.
+type ContextDNSRecordSetEditor interface {
+
+	// ReplaceRecordSetContext makes the address record set point to exactly the given IP addresses.
+	ReplaceRecordSetContext(ctx context.Context, domain, subDomainName, recordType string, timeToLive int, ips []net.IP) (RecordSetChange, error)
+
+	// AddToRecordSetContext adds the given IP address to the address record set.
+	AddToRecordSetContext(ctx context.Context, domain, subDomainName string, timeToLive int, ip net.IP) (RecordSetChange, error)
+
+	// RemoveFromRecordSetContext removes the given IP address from the address record set.
+	RemoveFromRecordSetContext(ctx context.Context, domain, subDomainName string, ip net.IP) (RecordSetChange, error)
+}
+
+// The ContextDNSTypedRecordEditor interface offers functions for editing
+// records of other types than A and AAAA that can be cancelled with a context.
+type ContextDNSTypedRecordEditor interface {
+
+	// CreateRecordContext creates the given record and returns its ID.
+	CreateRecordContext(ctx context.Context, domain, name string, timeToLive int, record TypedRecord) (string, error)
+
+	// UpdateRecordContext replaces the given current record with the updated one.
+	UpdateRecordContext(ctx context.Context, domain, name string, timeToLive int, current, updated TypedRecord) error
+
+	// DeleteRecordContext removes the given record.
+	DeleteRecordContext(ctx context.Context, domain, name string, record TypedRecord) error
+}
+
+// The ContextDNSRecordEditor interface provides functions for editing DNS
+// records that can be cancelled with a context.
+type ContextDNSRecordEditor interface {
+	ContextDNSRecordCreator
+	ContextDNSRecordUpdater
+	ContextDNSRecordUpserter
+	ContextDNSRecordDeleter
+}
+
 // NewDNSEditor creates an new DNSRecordEditor instance.
+// The returned editor also implements the ContextDNSRecordEditor, the
+// DNSRecordTTLUpdater, the DNSDualStackUpdater, the DNSRecordSetEditor
+// and the DNSTypedRecordEditor interface.
 func NewDNSEditor(client DNSClient, infoProvider DNSInfoProvider) DNSRecordEditor {
 	return &DNSEditor{client, infoProvider}
 }
@@ -49,114 +206,270 @@ type DNSEditor struct {
 	infoProvider DNSInfoProvider
 }
 
-// CreateSubdomain creates an address record for the given domain
-func (editor *DNSEditor) CreateSubdomain(domain, subdomain string, timeToLive int, ip net.IP) error {
+// CreateSubdomain creates an address record for the given domain and
+// returns the ID of the new record. If an address record of the same
+// type exists already a *RecordExistsError is returned.
+func (editor *DNSEditor) CreateSubdomain(domain, subdomain string, timeToLive int, ip net.IP) (string, error) {
+	return editor.CreateSubdomainContext(context.Background(), domain, subdomain, timeToLive, ip)
+}
+
+// CreateSubdomainContext creates an address record for the given domain and
+// returns the ID of the new record. If an address record of the same
+// type exists already a *RecordExistsError is returned.
+func (editor *DNSEditor) CreateSubdomainContext(ctx context.Context, domain, subdomain string, timeToLive int, ip net.IP) (string, error) {
 
 	// validate parameters
 	if isValidDomain(domain) == false {
-		return fmt.Errorf("The domain name is invalid: %q", domain)
+		return "", newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
 	}
 
 	if isValidSubdomain(subdomain) == false {
-		return fmt.Errorf("The domain name is invalid: %q", subdomain)
+		return "", newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
 	}
 
-	if ip == nil {
-		return fmt.Errorf("No ip supplied")
+	if err := validateTTL(timeToLive); err != nil {
+		return "", err
 	}
 
-	// check if the record already exists
-	recordType := getDNSRecordTypeByIP(ip)
-	if _, err := editor.infoProvider.GetSubdomainRecord(domain, subdomain, recordType); err != nil {
-		return fmt.Errorf("No address record of type %q found for %q", recordType, subdomain)
+	if ip == nil {
+		return "", newKindError(ErrInvalidArgument, "No ip supplied")
 	}
 
-	// create record
-	changeRecord := &dnsimple.ChangeRecord{
-		Name:  subdomain,
-		Value: ip.String(),
-		Type:  recordType,
-		Ttl:   fmt.Sprintf("%s", timeToLive),
+	// make sure the record does not exist yet
+	recordType := getDNSRecordTypeByIP(ip)
+	existingRecord, err := editor.contextInfoProvider().GetSubdomainRecordContext(ctx, domain, subdomain, recordType)
+	if err == nil {
+		return "", &RecordExistsError{domain, subdomain, recordType, existingRecord.StringId()}
 	}
 
-	_, createError := editor.client.CreateRecord(domain, changeRecord)
-	if createError != nil {
-		return createError
+	if !isRecordNotFound(err) {
+		return "", err
 	}
 
-	return nil
+	return editor.createAddressRecord(ctx, domain, subdomain, recordType, timeToLive, ip)
 }
 
 // UpdateSubdomain updates the IP address of the given domain/subdomain.
 func (editor *DNSEditor) UpdateSubdomain(domain, subdomain string, ip net.IP) error {
+	return editor.UpdateSubdomainContext(context.Background(), domain, subdomain, ip)
+}
+
+// UpdateSubdomainContext updates the IP address of the given domain/subdomain.
+// An error matching ErrMultipleRecords is returned if the subdomain has
+// several address records of the same type (see ReplaceRecordSet).
+func (editor *DNSEditor) UpdateSubdomainContext(ctx context.Context, domain, subdomain string, ip net.IP) error {
 
 	// validate parameters
 	if isValidDomain(domain) == false {
-		return fmt.Errorf("The domain name is invalid: %q", domain)
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
 	}
 
 	if isValidSubdomain(subdomain) == false {
-		return fmt.Errorf("The domain name is invalid: %q", subdomain)
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
 	}
 
 	if ip == nil {
-		return fmt.Errorf("No ip supplied")
+		return newKindError(ErrInvalidArgument, "No ip supplied")
 	}
 
 	// get the subdomain record
 	recordType := getDNSRecordTypeByIP(ip)
-	subdomainRecord, err := editor.infoProvider.GetSubdomainRecord(domain, subdomain, recordType)
+	subdomainRecord, err := editor.getAddressRecord(ctx, domain, subdomain, recordType)
 	if err != nil {
-		return fmt.Errorf("No address record of type %q found for %q", recordType, subdomain)
+		return err
 	}
 
 	// check if an update is necessary
-	if subdomainRecord.Content == ip.String() {
-		return fmt.Errorf("No update required. IP address did not change (%s).", subdomainRecord.Content)
+	if ip.Equal(net.ParseIP(subdomainRecord.Content)) {
+		return newKindError(ErrNoUpdateRequired, "No update required. IP address did not change (%s).", subdomainRecord.Content)
 	}
 
-	// update the record
-	changeRecord := &dnsimple.ChangeRecord{
-		Name:  subdomainRecord.Name,
-		Value: ip.String(),
-		Type:  subdomainRecord.RecordType,
-		Ttl:   fmt.Sprintf("%d", subdomainRecord.Ttl),
+	return editor.updateAddressRecord(ctx, domain, subdomainRecord, ip.String(), subdomainRecord.Ttl)
+}
+
+// UpdateSubdomainWithTTL updates the IP address and the time to live of the given domain/subdomain.
+func (editor *DNSEditor) UpdateSubdomainWithTTL(domain, subdomain string, timeToLive int, ip net.IP) error {
+	return editor.UpdateSubdomainWithTTLContext(context.Background(), domain, subdomain, timeToLive, ip)
+}
+
+// UpdateSubdomainWithTTLContext updates the IP address and the time to live of the given domain/subdomain.
+// An error matching ErrNoUpdateRequired is returned if neither the IP address nor the TTL changed.
+func (editor *DNSEditor) UpdateSubdomainWithTTLContext(ctx context.Context, domain, subdomain string, timeToLive int, ip net.IP) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
 	}
 
-	_, updateError := editor.client.UpdateRecord(domain, fmt.Sprintf("%v", subdomainRecord.Id), changeRecord)
-	if updateError != nil {
-		return updateError
+	if isValidSubdomain(subdomain) == false {
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
 	}
 
-	return nil
+	if err := validateTTL(timeToLive); err != nil {
+		return err
+	}
+
+	if ip == nil {
+		return newKindError(ErrInvalidArgument, "No ip supplied")
+	}
+
+	// get the subdomain record
+	recordType := getDNSRecordTypeByIP(ip)
+	subdomainRecord, err := editor.getAddressRecord(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	// check if an update is necessary
+	if ip.Equal(net.ParseIP(subdomainRecord.Content)) && subdomainRecord.Ttl == int64(timeToLive) {
+		return newKindError(ErrNoUpdateRequired, "No update required. IP address (%s) and TTL (%d) did not change.", subdomainRecord.Content, subdomainRecord.Ttl)
+	}
+
+	return editor.updateAddressRecord(ctx, domain, subdomainRecord, ip.String(), int64(timeToLive))
+}
+
+// UpdateSubdomainTTL updates the time to live of the address record of the given domain/subdomain.
+func (editor *DNSEditor) UpdateSubdomainTTL(domain, subdomain string, recordType string, timeToLive int) error {
+	return editor.UpdateSubdomainTTLContext(context.Background(), domain, subdomain, recordType, timeToLive)
+}
+
+// UpdateSubdomainTTLContext updates the time to live of the address record of the given domain/subdomain.
+// The IP address of the record is not changed. An error matching ErrNoUpdateRequired is returned if
+// the record has the given TTL already.
+func (editor *DNSEditor) UpdateSubdomainTTLContext(ctx context.Context, domain, subdomain string, recordType string, timeToLive int) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
+	}
+
+	if recordType != "AAAA" && recordType != "A" {
+		return newKindError(ErrInvalidArgument, "The given record type is invalid: %q", recordType)
+	}
+
+	if err := validateTTL(timeToLive); err != nil {
+		return err
+	}
+
+	// get the subdomain record
+	subdomainRecord, err := editor.getAddressRecord(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	// check if an update is necessary
+	if subdomainRecord.Ttl == int64(timeToLive) {
+		return newKindError(ErrNoUpdateRequired, "No update required. TTL did not change (%d).", subdomainRecord.Ttl)
+	}
+
+	return editor.updateAddressRecord(ctx, domain, subdomainRecord, subdomainRecord.Content, int64(timeToLive))
 }
 
 // DeleteSubdomain deletes the address record of the given domain
 func (editor *DNSEditor) DeleteSubdomain(domain, subdomain string, recordType string) error {
+	return editor.DeleteSubdomainContext(context.Background(), domain, subdomain, recordType)
+}
+
+// DeleteSubdomainContext deletes the address record of the given domain.
+// An error matching ErrMultipleRecords is returned if the subdomain has
+// several address records of the given type (see ReplaceRecordSet).
+func (editor *DNSEditor) DeleteSubdomainContext(ctx context.Context, domain, subdomain string, recordType string) error {
 
 	// validate parameters
 	if isValidDomain(domain) == false {
-		return fmt.Errorf("The domain name is invalid: %q", domain)
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
 	}
 
 	if isValidSubdomain(subdomain) == false {
-		return fmt.Errorf("The domain name is invalid: %q", subdomain)
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
 	}
 
 	if recordType != "AAAA" && recordType != "A" {
-		return fmt.Errorf("The given record type is invalid: %q", subdomain)
+		return newKindError(ErrInvalidArgument, "The given record type is invalid: %q", recordType)
 	}
 
 	// check if the record already exists
-	subdomainRecord, subdomainError := editor.infoProvider.GetSubdomainRecord(domain, subdomain, recordType)
+	subdomainRecord, subdomainError := editor.getAddressRecord(ctx, domain, subdomain, recordType)
 	if subdomainError != nil {
-		return fmt.Errorf("No address record of type %q found for %q", recordType, subdomain)
+		return subdomainError
 	}
 
-	deleteError := editor.client.DestroyRecord(domain, fmt.Sprintf("%d", subdomainRecord.Id))
+	deleteError := editor.contextClient().DestroyRecordContext(ctx, domain, fmt.Sprintf("%d", subdomainRecord.Id))
+	editor.invalidateCache(domain)
 	if deleteError != nil {
 		return deleteError
 	}
 
 	return nil
 }
+
+// createAddressRecord creates an address record with the given values
+// and returns its ID.
+func (editor *DNSEditor) createAddressRecord(ctx context.Context, domain, subdomain, recordType string, timeToLive int, ip net.IP) (string, error) {
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  subdomain,
+		Value: ip.String(),
+		Type:  recordType,
+		Ttl:   fmt.Sprintf("%d", timeToLive),
+	}
+
+	id, createError := editor.contextClient().CreateRecordContext(ctx, domain, changeRecord)
+	editor.invalidateCache(domain)
+	if createError != nil {
+		return "", createError
+	}
+
+	return id, nil
+}
+
+// updateAddressRecord sets the content and the time to live of the given
+// record. The name and type of the record are not changed.
+func (editor *DNSEditor) updateAddressRecord(ctx context.Context, domain string, record dnsimple.Record, content string, timeToLive int64) error {
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  record.Name,
+		Value: content,
+		Type:  record.RecordType,
+		Ttl:   fmt.Sprintf("%d", timeToLive),
+	}
+
+	_, updateError := editor.contextClient().UpdateRecordContext(ctx, domain, fmt.Sprintf("%v", record.Id), changeRecord)
+	editor.invalidateCache(domain)
+	if updateError != nil {
+		return updateError
+	}
+
+	return nil
+}
+
+// validateTTL returns an error if the given time to live is not between
+// MinTTL and MaxTTL.
+func validateTTL(timeToLive int) error {
+	if timeToLive < MinTTL || timeToLive > MaxTTL {
+		return newKindError(ErrInvalidArgument, "The TTL must be between %d and %d seconds: %d", MinTTL, MaxTTL, timeToLive)
+	}
+
+	return nil
+}
+
+// invalidateCache removes the cached records of the given domain if the
+// info provider of this editor caches records. It is called after every
+// write, even a failed one, because the API might have applied it.
+func (editor *DNSEditor) invalidateCache(domain string) {
+	if cache, ok := editor.infoProvider.(CacheInvalidator); ok {
+		cache.Invalidate(domain)
+	}
+}
+
+// contextClient returns the DNS client of this editor as a ContextDNSClient.
+func (editor *DNSEditor) contextClient() ContextDNSClient {
+	return AsContextDNSClient(editor.client)
+}
+
+// contextInfoProvider returns the info provider of this editor as a ContextDNSInfoProvider.
+func (editor *DNSEditor) contextInfoProvider() ContextDNSInfoProvider {
+	return AsContextDNSInfoProvider(editor.infoProvider)
+}
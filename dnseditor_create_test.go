@@ -5,6 +5,7 @@
 package deens
 
 import (
+	"errors"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 	"net"
@@ -13,10 +14,10 @@ import (
 
 // testDNSCreator creates DNS records.
 type testDNSCreator struct {
-	createSubdomainFunc func(domain, subdomain string, timeToLive int, ip net.IP) error
+	createSubdomainFunc func(domain, subdomain string, timeToLive int, ip net.IP) (string, error)
 }
 
-func (editor *testDNSCreator) CreateSubdomain(domain, subdomain string, timeToLive int, ip net.IP) error {
+func (editor *testDNSCreator) CreateSubdomain(domain, subdomain string, timeToLive int, ip net.IP) (string, error) {
 	return editor.createSubdomainFunc(domain, subdomain, timeToLive, ip)
 }
 
@@ -34,51 +35,101 @@ func Test_CreateSubdomain_ParametersInvalid_ErrorIsReturned(t *testing.T) {
 		{"", "", 600, net.ParseIP("::1")},
 		{" ", " ", 600, net.ParseIP("::1")},
 		{"example.com", "www", 600, nil},
+		{"example.com", "www", 0, net.ParseIP("::1")},
+		{"example.com", "www", -600, net.ParseIP("::1")},
+		{"example.com", "www", MinTTL - 1, net.ParseIP("::1")},
 	}
 	editor := DNSEditor{}
 
 	for _, input := range inputs {
 
 		// act
-		err := editor.CreateSubdomain(input.domain, input.subdomain, input.ttl, input.ip)
+		_, err := editor.CreateSubdomain(input.domain, input.subdomain, input.ttl, input.ip)
 
 		// assert
-		if err == nil {
+		if !errors.Is(err, ErrInvalidArgument) {
 			t.Fail()
-			t.Logf("CreateSubdomain(%q, %q, %q, %q) should return an error.", input.domain, input.subdomain, input.ttl, input.ip)
+			t.Logf("CreateSubdomain(%q, %q, %d, %q) should return an invalid argument error but returned %v.", input.domain, input.subdomain, input.ttl, input.ip, err)
 		}
 	}
 }
 
-// CreateSubdomain should return an error if the given subdomain does not exist.
-func Test_CreateSubdomain_ValidParameters_SubdomainNotFound_ErrorIsReturned(t *testing.T) {
+// CreateSubdomain should return an error if the existing records cannot be fetched.
+func Test_CreateSubdomain_ValidParameters_LookupFails_ErrorIsReturned(t *testing.T) {
 	// arrange
 	domain := "example.com"
 	subdomain := "www"
 	ttl := 600
 	ip := net.ParseIP("::1")
 
+	dnsClient := &testDNSClient{
+		createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+			t.Fail()
+			t.Logf("CreateRecord() should not be called if the lookup failed")
+			return "", nil
+		},
+	}
+
 	infoProvider := &testDNSInfoProvider{
 		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, fmt.Errorf("")
+			return dnsimple.Record{}, fmt.Errorf("Connection reset")
 		},
 	}
 
 	editor := DNSEditor{
+		client:       dnsClient,
 		infoProvider: infoProvider,
 	}
 
 	// act
-	err := editor.CreateSubdomain(domain, subdomain, ttl, ip)
+	_, err := editor.CreateSubdomain(domain, subdomain, ttl, ip)
 
 	// assert
 	if err == nil {
 		t.Fail()
-		t.Logf("CreateSubdomain(%q, %q, %q, %q) should return an error if the subdomain does not exist.", domain, subdomain, ttl, ip)
+		t.Logf("CreateSubdomain(%q, %q, %d, %q) should return an error if the lookup failed.", domain, subdomain, ttl, ip)
+	}
+}
+
+// CreateSubdomain should return a RecordExistsError if the given subdomain exists already.
+func Test_CreateSubdomain_ValidParameters_SubdomainExists_RecordExistsErrorIsReturned(t *testing.T) {
+	// arrange
+	domain := "example.com"
+	subdomain := "www"
+	ttl := 600
+	ip := net.ParseIP("::1")
+
+	dnsClient := &testDNSClient{
+		createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+			t.Fail()
+			t.Logf("CreateRecord() should not be called for an existing record")
+			return "", nil
+		},
+	}
+
+	infoProvider := &testDNSInfoProvider{
+		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
+			return dnsimple.Record{Id: 7, Name: subdomain, RecordType: recordType, Content: "::2"}, nil
+		},
+	}
+
+	editor := DNSEditor{
+		client:       dnsClient,
+		infoProvider: infoProvider,
+	}
+
+	// act
+	_, err := editor.CreateSubdomain(domain, subdomain, ttl, ip)
+
+	// assert
+	var existsError *RecordExistsError
+	if !errors.Is(err, ErrAlreadyExists) || !errors.As(err, &existsError) || existsError.RecordID != "7" || existsError.RecordType != "AAAA" {
+		t.Fail()
+		t.Logf("CreateSubdomain(%q, %q, %d, %q) should return a RecordExistsError for the record 7 but returned %v.", domain, subdomain, ttl, ip, err)
 	}
 }
 
-func Test_CreateSubdomain_ValidParameters_SubdomainExists_DNSRecordCreationFails_ErrorIsReturned(t *testing.T) {
+func Test_CreateSubdomain_ValidParameters_SubdomainNotFound_DNSRecordCreationFails_ErrorIsReturned(t *testing.T) {
 	// arrange
 	domain := "example.com"
 	subdomain := "www"
@@ -93,7 +144,7 @@ func Test_CreateSubdomain_ValidParameters_SubdomainExists_DNSRecordCreationFails
 
 	infoProvider := &testDNSInfoProvider{
 		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, nil
+			return dnsimple.Record{}, &RecordNotFoundError{domain, subdomain, recordType}
 		},
 	}
 
@@ -103,31 +154,33 @@ func Test_CreateSubdomain_ValidParameters_SubdomainExists_DNSRecordCreationFails
 	}
 
 	// act
-	err := editor.CreateSubdomain(domain, subdomain, ttl, ip)
+	_, err := editor.CreateSubdomain(domain, subdomain, ttl, ip)
 
 	// assert
 	if err == nil {
 		t.Fail()
-		t.Logf("CreateSubdomain(%q, %q, %q, %q) should return an error of the record creation failed at the DNS client.", domain, subdomain, ip, ttl)
+		t.Logf("CreateSubdomain(%q, %q, %d, %q) should return an error of the record creation failed at the DNS client.", domain, subdomain, ttl, ip)
 	}
 }
 
-func Test_CreateSubdomain_ValidParameters_SubdomainExists_DNSRecordCreationSucceeds_NoErrorIsReturned(t *testing.T) {
+func Test_CreateSubdomain_ValidParameters_SubdomainNotFound_DNSRecordCreationSucceeds_IDIsReturned(t *testing.T) {
 	// arrange
 	domain := "example.com"
 	subdomain := "www"
 	ttl := 3600
 	ip := net.ParseIP("::1")
 
+	var createdRecord *dnsimple.ChangeRecord
 	dnsClient := &testDNSClient{
 		createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
-			return "", nil
+			createdRecord = opts
+			return "42", nil
 		},
 	}
 
 	infoProvider := &testDNSInfoProvider{
 		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, nil
+			return dnsimple.Record{}, &RecordNotFoundError{domain, subdomain, recordType}
 		},
 	}
 
@@ -137,11 +190,74 @@ func Test_CreateSubdomain_ValidParameters_SubdomainExists_DNSRecordCreationSucce
 	}
 
 	// act
-	err := editor.CreateSubdomain(domain, subdomain, ttl, ip)
+	id, err := editor.CreateSubdomain(domain, subdomain, ttl, ip)
+
+	// assert
+	if err != nil || id != "42" {
+		t.Fail()
+		t.Logf("CreateSubdomain(%q, %q, %d, %q) should return the ID of the new record but returned %q, %v.", domain, subdomain, ttl, ip, id, err)
+	}
+
+	expected := dnsimple.ChangeRecord{Name: "www", Value: "::1", Type: "AAAA", Ttl: "3600"}
+	if createdRecord == nil || *createdRecord != expected {
+		t.Fail()
+		t.Logf("CreateSubdomain() should create the record %+v but created %+v", expected, createdRecord)
+	}
+}
+
+// Info providers that report missing records with a plain ErrNotFound should lead to a new record.
+func Test_CreateSubdomain_InfoProviderReturnsErrNotFound_RecordIsCreated(t *testing.T) {
+	// arrange
+	created := false
+	editor := DNSEditor{
+		client: &testDNSClient{
+			createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+				created = true
+				return "42", nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordFunc: func(domain, subdomain, recordType string) (dnsimple.Record, error) {
+				return dnsimple.Record{}, fmt.Errorf("lookup failed: %w", ErrNotFound)
+			},
+		},
+	}
+
+	// act
+	id, err := editor.CreateSubdomain("example.com", "www", 3600, net.ParseIP("::1"))
+
+	// assert
+	if err != nil || id != "42" || !created {
+		t.Fail()
+		t.Logf("CreateSubdomain() should create the record but returned %q, %v", id, err)
+	}
+}
+
+// A 404 response of the API means that the domain does not exist, so no record should be created.
+func Test_CreateSubdomain_DomainNotFound_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client: &testDNSClient{
+			createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+				t.Fail()
+				t.Logf("CreateRecord() should not be called for an unknown domain")
+				return "", nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordFunc: func(domain, subdomain, recordType string) (dnsimple.Record, error) {
+				return dnsimple.Record{}, &APIError{StatusCode: 404, Status: "404 Not Found"}
+			},
+		},
+	}
+
+	// act
+	_, err := editor.CreateSubdomain("example.com", "www", 3600, net.ParseIP("::1"))
 
 	// assert
-	if err != nil {
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
 		t.Fail()
-		t.Logf("CreateSubdomain(%q, %q, %q, %q) should not return an error if the DNS record creation succeeded.", domain, subdomain, ttl, ip)
+		t.Logf("CreateSubdomain() should return the API error but returned %v", err)
 	}
 }
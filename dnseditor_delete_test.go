@@ -5,6 +5,7 @@
 package deens
 
 import (
+	"errors"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 	"testing"
@@ -56,8 +57,8 @@ func Test_DeleteSubdomain_ValidParameters_SubdomainNotFound_ErrorIsReturned(t *t
 	recordType := "AAAA"
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, fmt.Errorf("Subdomain does not exist")
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return nil, fmt.Errorf("Subdomain does not exist")
 		},
 	}
 
@@ -88,8 +89,8 @@ func Test_DeleteSubdomain_ValidParameters_SubdomainExists_DNSRecordDeleteFails_E
 	}
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, nil
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: subdomain, RecordType: "AAAA", Content: "::2"}}, nil
 		},
 	}
 
@@ -121,8 +122,8 @@ func Test_DeleteSubdomain_ValidParameters_SubdomainExists_DNSRecordDeleteSucceed
 	}
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, nil
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: subdomain, RecordType: "AAAA", Content: "::2"}}, nil
 		},
 	}
 
@@ -140,3 +141,19 @@ func Test_DeleteSubdomain_ValidParameters_SubdomainExists_DNSRecordDeleteSucceed
 		t.Logf("DeleteSubdomain(%q, %q, %q) should not return an error if the DNS record deletion succeeds.", domain, subdomain, recordType)
 	}
 }
+
+// DeleteSubdomain should not delete an arbitrary record if several records match.
+func Test_DeleteSubdomain_MultipleRecords_ErrorIsReturned(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords...)
+
+	// act
+	err := editor.DeleteSubdomain("example.com", "www", "A")
+
+	// assert
+	if !errors.Is(err, ErrMultipleRecords) || len(deleted) != 0 {
+		t.Fail()
+		t.Logf("DeleteSubdomain() should return ErrMultipleRecords and delete nothing but returned %v (deleted: %v)", err, deleted)
+	}
+}
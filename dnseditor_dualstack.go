@@ -0,0 +1,166 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+const (
+	// UpsertDeleted means that the records of an address family were
+	// deleted because the host lost its address (see UpdateDualStack).
+	UpsertDeleted UpsertAction = "deleted"
+
+	// UpsertSkipped means that an address family was not changed
+	// because no address was given (see UpdateDualStack).
+	UpsertSkipped UpsertAction = "skipped"
+)
+
+// DualStackUpdate contains the addresses for UpdateDualStack.
+type DualStackUpdate struct {
+	// IPv4 is the new IPv4 address of the host. The A record is not
+	// changed if it is nil.
+	IPv4 net.IP
+
+	// IPv6 is the new IPv6 address of the host. The AAAA record is not
+	// changed if it is nil, unless DeleteMissingIPv6 is set.
+	IPv6 net.IP
+
+	// TimeToLive is the time to live (in seconds) of new records.
+	// Existing records keep their TTL. It is required unless only
+	// missing IPv6 records are deleted.
+	TimeToLive int
+
+	// DeleteMissingIPv6 deletes the AAAA records of the subdomain if
+	// IPv6 is nil (e.g. because the host lost its IPv6 connectivity).
+	DeleteMissingIPv6 bool
+}
+
+// AddressFamilyResult is the outcome of UpdateDualStack for one address family.
+type AddressFamilyResult struct {
+	// Action is the action that was taken.
+	Action UpsertAction
+
+	// RecordID is the ID of the created, updated or unchanged record.
+	// It is empty for skipped families and deleted records.
+	RecordID string
+
+	// Err is the error that occurred while changing the records of the
+	// address family. Action and RecordID are not set if it is not nil.
+	Err error
+}
+
+// DualStackResult is the result of UpdateDualStack.
+type DualStackResult struct {
+	// IPv4 is the outcome for the A record.
+	IPv4 AddressFamilyResult
+
+	// IPv6 is the outcome for the AAAA record.
+	IPv6 AddressFamilyResult
+}
+
+// Err returns the error of the failed address families or nil if
+// both families were converged. The error wraps the IPv4 error if
+// both families failed.
+func (result DualStackResult) Err() error {
+	switch {
+	case result.IPv4.Err != nil && result.IPv6.Err != nil:
+		return fmt.Errorf("IPv4: %w (IPv6: %s)", result.IPv4.Err, result.IPv6.Err.Error())
+
+	case result.IPv4.Err != nil:
+		return fmt.Errorf("IPv4: %w", result.IPv4.Err)
+
+	case result.IPv6.Err != nil:
+		return fmt.Errorf("IPv6: %w", result.IPv6.Err)
+	}
+
+	return nil
+}
+
+// UpdateDualStack makes the A and the AAAA record of the given subdomain
+// point to the given addresses. Both families are changed independently;
+// the result reports the outcome of each of them.
+func (editor *DNSEditor) UpdateDualStack(domain, subdomain string, update DualStackUpdate) (DualStackResult, error) {
+	return editor.UpdateDualStackContext(context.Background(), domain, subdomain, update)
+}
+
+// UpdateDualStackContext makes the A and the AAAA record of the given
+// subdomain point to the given addresses. Missing records are created and
+// existing records are updated (see UpsertSubdomain). Both families are
+// changed independently, so a failure of one family does not prevent the
+// update of the other. The returned error is DualStackResult.Err.
+func (editor *DNSEditor) UpdateDualStackContext(ctx context.Context, domain, subdomain string, update DualStackUpdate) (DualStackResult, error) {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return DualStackResult{}, newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return DualStackResult{}, newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
+	}
+
+	if update.IPv4 != nil || update.IPv6 != nil {
+		if err := validateTTL(update.TimeToLive); err != nil {
+			return DualStackResult{}, err
+		}
+	}
+
+	if update.IPv4 != nil && update.IPv4.To4() == nil {
+		return DualStackResult{}, newKindError(ErrInvalidArgument, "The IPv4 address is invalid: %q", update.IPv4)
+	}
+
+	if update.IPv6 != nil && update.IPv6.To4() != nil {
+		return DualStackResult{}, newKindError(ErrInvalidArgument, "The IPv6 address is invalid: %q", update.IPv6)
+	}
+
+	if update.IPv4 == nil && update.IPv6 == nil && !update.DeleteMissingIPv6 {
+		return DualStackResult{}, newKindError(ErrInvalidArgument, "No ip supplied")
+	}
+
+	// converge both families
+	result := DualStackResult{
+		IPv4: editor.upsertAddressFamily(ctx, domain, subdomain, update.TimeToLive, update.IPv4),
+		IPv6: editor.upsertAddressFamily(ctx, domain, subdomain, update.TimeToLive, update.IPv6),
+	}
+
+	if update.IPv6 == nil && update.DeleteMissingIPv6 {
+		result.IPv6 = editor.deleteAddressFamily(ctx, domain, subdomain, "AAAA")
+	}
+
+	return result, result.Err()
+}
+
+// upsertAddressFamily creates or updates the address record of the
+// given subdomain. The family is skipped if ip is nil.
+func (editor *DNSEditor) upsertAddressFamily(ctx context.Context, domain, subdomain string, timeToLive int, ip net.IP) AddressFamilyResult {
+	if ip == nil {
+		return AddressFamilyResult{Action: UpsertSkipped}
+	}
+
+	upsertResult, err := editor.UpsertSubdomainContext(ctx, domain, subdomain, timeToLive, ip)
+	if err != nil {
+		return AddressFamilyResult{Err: err}
+	}
+
+	return AddressFamilyResult{Action: upsertResult.Action, RecordID: upsertResult.RecordID}
+}
+
+// deleteAddressFamily deletes all address records of the given type
+// and subdomain.
+func (editor *DNSEditor) deleteAddressFamily(ctx context.Context, domain, subdomain, recordType string) AddressFamilyResult {
+	change, err := editor.ReplaceRecordSetContext(ctx, domain, subdomain, recordType, MinTTL, nil)
+	if err != nil {
+		return AddressFamilyResult{Err: err}
+	}
+
+	if change.IsEmpty() {
+		return AddressFamilyResult{Action: UpsertUnchanged}
+	}
+
+	return AddressFamilyResult{Action: UpsertDeleted}
+}
@@ -0,0 +1,204 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"testing"
+)
+
+// newDualStackTestEditor returns an editor for the given records that
+// logs all changes in the given list.
+func newDualStackTestEditor(changes *[]string, records ...dnsimple.Record) (DNSEditor, *testDNSClient) {
+	client := &testDNSClient{
+		getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return records, nil
+		},
+		createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+			*changes = append(*changes, "create "+opts.Type+" "+opts.Value)
+			return "100", nil
+		},
+		updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+			*changes = append(*changes, "update "+id+" "+opts.Value)
+			return id, nil
+		},
+		destroyRecordFunc: func(domain string, id string) error {
+			*changes = append(*changes, "delete "+id)
+			return nil
+		},
+	}
+
+	return DNSEditor{client, NewDNSInfoProvider(client)}, client
+}
+
+// Invalid addresses should be rejected before any request is sent.
+func Test_UpdateDualStack_InvalidAddresses_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []DualStackUpdate{
+		{IPv4: net.ParseIP("::1"), TimeToLive: 600},
+		{IPv6: net.ParseIP("127.0.0.1"), TimeToLive: 600},
+		{TimeToLive: 600},
+		{IPv4: net.ParseIP("127.0.0.1"), TimeToLive: -1},
+	}
+
+	var changes []string
+	editor, _ := newDualStackTestEditor(&changes)
+
+	for _, input := range inputs {
+
+		// act
+		_, err := editor.UpdateDualStack("example.com", "home", input)
+
+		// assert
+		if !errors.Is(err, ErrInvalidArgument) || len(changes) != 0 {
+			t.Fail()
+			t.Logf("UpdateDualStack(%+v) should return ErrInvalidArgument but returned %v", input, err)
+		}
+	}
+}
+
+// Both records should be converged and reported separately.
+func Test_UpdateDualStack_BothAddresses_RecordsAreConverged(t *testing.T) {
+	// arrange
+	var changes []string
+	editor, _ := newDualStackTestEditor(&changes,
+		dnsimple.Record{Id: 1, Name: "home", RecordType: "A", Content: "127.0.0.1", Ttl: 600},
+	)
+
+	// act
+	result, err := editor.UpdateDualStack("example.com", "home", DualStackUpdate{
+		IPv4:       net.ParseIP("127.0.0.2"),
+		IPv6:       net.ParseIP("2001:db8::1"),
+		TimeToLive: 600,
+	})
+
+	// assert
+	if err != nil || result.IPv4.Action != UpsertUpdated || result.IPv4.RecordID != "1" || result.IPv6.Action != UpsertCreated || result.IPv6.RecordID != "100" {
+		t.Fail()
+		t.Logf("UpdateDualStack() should update the A and create the AAAA record but returned %+v, %v", result, err)
+	}
+
+	if len(changes) != 2 || changes[0] != "update 1 127.0.0.2" || changes[1] != "create AAAA 2001:db8::1" {
+		t.Fail()
+		t.Logf("UpdateDualStack() sent unexpected requests: %v", changes)
+	}
+}
+
+// The AAAA records should be deleted if the host lost its IPv6 address and the option is set.
+func Test_UpdateDualStack_IPv6Missing_AAAARecordIsDeletedOnlyIfRequested(t *testing.T) {
+	// arrange
+	records := []dnsimple.Record{
+		{Id: 1, Name: "home", RecordType: "A", Content: "127.0.0.1", Ttl: 600},
+		{Id: 2, Name: "home", RecordType: "AAAA", Content: "2001:db8::1", Ttl: 600},
+	}
+
+	var keptChanges, deletedChanges []string
+	keepingEditor, _ := newDualStackTestEditor(&keptChanges, records...)
+	deletingEditor, _ := newDualStackTestEditor(&deletedChanges, records...)
+
+	// act
+	keptResult, keptError := keepingEditor.UpdateDualStack("example.com", "home", DualStackUpdate{IPv4: net.ParseIP("127.0.0.1"), TimeToLive: 600})
+	deletedResult, deletedError := deletingEditor.UpdateDualStack("example.com", "home", DualStackUpdate{IPv4: net.ParseIP("127.0.0.1"), TimeToLive: 600, DeleteMissingIPv6: true})
+
+	// assert
+	if keptError != nil || keptResult.IPv4.Action != UpsertUnchanged || keptResult.IPv6.Action != UpsertSkipped || len(keptChanges) != 0 {
+		t.Fail()
+		t.Logf("UpdateDualStack() should keep the AAAA record but returned %+v, %v (requests: %v)", keptResult, keptError, keptChanges)
+	}
+
+	if deletedError != nil || deletedResult.IPv6.Action != UpsertDeleted || len(deletedChanges) != 1 || deletedChanges[0] != "delete 2" {
+		t.Fail()
+		t.Logf("UpdateDualStack() should delete the AAAA record but returned %+v, %v (requests: %v)", deletedResult, deletedError, deletedChanges)
+	}
+}
+
+// A failure of one family should not prevent the update of the other.
+func Test_UpdateDualStack_IPv4Fails_IPv6IsUpdated(t *testing.T) {
+	// arrange
+	var changes []string
+	editor, client := newDualStackTestEditor(&changes,
+		dnsimple.Record{Id: 1, Name: "home", RecordType: "A", Content: "127.0.0.1", Ttl: 600},
+		dnsimple.Record{Id: 2, Name: "home", RecordType: "AAAA", Content: "2001:db8::1", Ttl: 600},
+	)
+
+	updateRecord := client.updateRecordFunc
+	client.updateRecordFunc = func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+		if opts.Type == "A" {
+			return "", newKindError(ErrServer, "Server error")
+		}
+
+		return updateRecord(domain, id, opts)
+	}
+
+	// act
+	result, err := editor.UpdateDualStack("example.com", "home", DualStackUpdate{
+		IPv4:       net.ParseIP("127.0.0.2"),
+		IPv6:       net.ParseIP("2001:db8::2"),
+		TimeToLive: 600,
+	})
+
+	// assert
+	if !errors.Is(err, ErrServer) || !errors.Is(result.IPv4.Err, ErrServer) {
+		t.Fail()
+		t.Logf("UpdateDualStack() should report the IPv4 error but returned %+v, %v", result, err)
+	}
+
+	if result.IPv6.Err != nil || result.IPv6.Action != UpsertUpdated || len(changes) != 1 || changes[0] != "update 2 2001:db8::2" {
+		t.Fail()
+		t.Logf("UpdateDualStack() should update the AAAA record but returned %+v (requests: %v)", result.IPv6, changes)
+	}
+}
+
+// A zero TTL should be rejected before any request is sent if records might be created.
+func Test_UpdateDualStack_ZeroTTL_ErrorIsReturned(t *testing.T) {
+	// arrange
+	var changes []string
+	editor, _ := newDualStackTestEditor(&changes)
+
+	// act
+	_, err := editor.UpdateDualStack("example.com", "home", DualStackUpdate{IPv4: net.ParseIP("127.0.0.1")})
+
+	// assert
+	if !errors.Is(err, ErrInvalidArgument) || len(changes) > 0 {
+		t.Fail()
+		t.Logf("UpdateDualStack() should reject a zero TTL but returned %v (changes: %v)", err, changes)
+	}
+}
+
+// Deleting the AAAA record should not require a TTL.
+func Test_UpdateDualStack_ZeroTTL_DeleteMissingIPv6_RecordIsDeleted(t *testing.T) {
+	// arrange
+	var changes []string
+	editor, _ := newDualStackTestEditor(&changes,
+		dnsimple.Record{Id: 2, Name: "home", RecordType: "AAAA", Content: "2001:db8::1", Ttl: 600},
+	)
+
+	// act
+	result, err := editor.UpdateDualStack("example.com", "home", DualStackUpdate{DeleteMissingIPv6: true})
+
+	// assert
+	if err != nil || result.IPv6.Action != UpsertDeleted || result.IPv4.Action != UpsertSkipped {
+		t.Fail()
+		t.Logf("UpdateDualStack() should delete the AAAA record but returned %+v, %v", result, err)
+	}
+}
+
+// The editor returned by NewDNSEditor should offer UpdateDualStack by type assertion.
+func Test_NewDNSEditor_ImplementsDualStackUpdater(t *testing.T) {
+	// arrange
+	editor := NewDNSEditor(&testDNSClient{}, &testDNSInfoProvider{})
+
+	// act
+	_, ok := editor.(DNSDualStackUpdater)
+	_, contextOK := editor.(ContextDNSDualStackUpdater)
+
+	// assert
+	if !ok || !contextOK {
+		t.Fail()
+		t.Logf("NewDNSEditor() should return an editor that implements DNSDualStackUpdater and ContextDNSDualStackUpdater")
+	}
+}
@@ -5,6 +5,7 @@
 package deens
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -33,11 +34,16 @@ func ExampleNewDNSEditor() {
 	timeToLive := 600
 	ip := net.ParseIP("127.0.0.1")
 
-	createSubdomainError := dnsEditor.CreateSubdomain(domain, subDomainName, timeToLive, ip)
+	recordID, createSubdomainError := dnsEditor.CreateSubdomain(domain, subDomainName, timeToLive, ip)
+	if errors.Is(createSubdomainError, ErrAlreadyExists) {
+		fmt.Fprintf(os.Stderr, "The subdomain %s.%s exists already", subDomainName, domain)
+		os.Exit(1)
+	}
+
 	if createSubdomainError != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create subdomain %s.%s: %s", subDomainName, domain, clientError.Error())
+		fmt.Fprintf(os.Stderr, "Failed to create subdomain %s.%s: %s", subDomainName, domain, createSubdomainError.Error())
 		os.Exit(1)
 	}
 
-	fmt.Fprintf(os.Stdout, "Created subdomain %s.%s", subDomainName, domain)
+	fmt.Fprintf(os.Stdout, "Created subdomain %s.%s (record %s)", subDomainName, domain, recordID)
 }
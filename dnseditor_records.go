@@ -0,0 +1,190 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+)
+
+// CreateRecord creates the given record with the given name (the empty
+// name is the domain itself) and returns the ID of the new record.
+func (editor *DNSEditor) CreateRecord(domain, name string, timeToLive int, record TypedRecord) (string, error) {
+	return editor.CreateRecordContext(context.Background(), domain, name, timeToLive, record)
+}
+
+// CreateRecordContext creates the given record with the given name (the empty
+// name is the domain itself) and returns the ID of the new record.
+// A *RecordExistsError is returned if the same record exists already. CNAME
+// records cannot be combined with other records of the same name.
+func (editor *DNSEditor) CreateRecordContext(ctx context.Context, domain, name string, timeToLive int, record TypedRecord) (string, error) {
+
+	// validate parameters
+	if err := validateTypedRecordArguments(domain, name, record); err != nil {
+		return "", err
+	}
+
+	if err := validateTTL(timeToLive); err != nil {
+		return "", err
+	}
+
+	// check the existing records of the same name
+	existingRecords, err := editor.contextInfoProvider().GetSubdomainRecordsContext(ctx, domain, name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, existingRecord := range existingRecords {
+		if recordMatches(existingRecord, record) {
+			return "", &RecordExistsError{domain, name, record.Type(), existingRecord.StringId()}
+		}
+
+		if existingRecord.RecordType == "CNAME" || record.Type() == "CNAME" {
+			return "", newKindError(ErrInvalidArgument, "A CNAME record cannot be combined with other records (%q has a %s record)", name, existingRecord.RecordType)
+		}
+	}
+
+	// create the record
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  name,
+		Value: record.Content(),
+		Type:  record.Type(),
+		Ttl:   fmt.Sprintf("%d", timeToLive),
+	}
+
+	var id string
+	var createError error
+	if priorityRecord, ok := record.(PriorityRecord); ok {
+		id, createError = createPriorityRecord(ctx, editor.contextClient(), domain, changeRecord, priorityRecord.Prio())
+	} else {
+		id, createError = editor.contextClient().CreateRecordContext(ctx, domain, changeRecord)
+	}
+
+	editor.invalidateCache(domain)
+	if createError != nil {
+		return "", createError
+	}
+
+	return id, nil
+}
+
+// UpdateRecord replaces the given current record with the updated record
+// of the same type and sets the given time to live.
+func (editor *DNSEditor) UpdateRecord(domain, name string, timeToLive int, current, updated TypedRecord) error {
+	return editor.UpdateRecordContext(context.Background(), domain, name, timeToLive, current, updated)
+}
+
+// UpdateRecordContext replaces the given current record with the updated record
+// of the same type and sets the given time to live. An error matching
+// ErrNotFound is returned if the current record does not exist and an error
+// matching ErrNoUpdateRequired if neither the record nor the TTL would change.
+func (editor *DNSEditor) UpdateRecordContext(ctx context.Context, domain, name string, timeToLive int, current, updated TypedRecord) error {
+
+	// validate parameters
+	if err := validateTypedRecordArguments(domain, name, current); err != nil {
+		return err
+	}
+
+	if err := validateTypedRecordArguments(domain, name, updated); err != nil {
+		return err
+	}
+
+	if current.Type() != updated.Type() {
+		return newKindError(ErrInvalidArgument, "The record type cannot be changed from %s to %s", current.Type(), updated.Type())
+	}
+
+	if err := validateTTL(timeToLive); err != nil {
+		return err
+	}
+
+	// get the current record
+	existingRecord, err := editor.findTypedRecord(ctx, domain, name, current)
+	if err != nil {
+		return err
+	}
+
+	// check if an update is necessary
+	if recordMatches(existingRecord, updated) && existingRecord.Ttl == int64(timeToLive) {
+		return newKindError(ErrNoUpdateRequired, "No update required. The %s record %q did not change.", updated.Type(), name)
+	}
+
+	// update the record
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  existingRecord.Name,
+		Value: updated.Content(),
+		Type:  existingRecord.RecordType,
+		Ttl:   fmt.Sprintf("%d", timeToLive),
+	}
+
+	var updateError error
+	if priorityRecord, ok := updated.(PriorityRecord); ok {
+		_, updateError = updatePriorityRecord(ctx, editor.contextClient(), domain, existingRecord.StringId(), changeRecord, priorityRecord.Prio())
+	} else {
+		_, updateError = editor.contextClient().UpdateRecordContext(ctx, domain, existingRecord.StringId(), changeRecord)
+	}
+
+	editor.invalidateCache(domain)
+	return updateError
+}
+
+// DeleteRecord deletes the given record with the given name.
+func (editor *DNSEditor) DeleteRecord(domain, name string, record TypedRecord) error {
+	return editor.DeleteRecordContext(context.Background(), domain, name, record)
+}
+
+// DeleteRecordContext deletes the given record with the given name. An error
+// matching ErrNotFound is returned if the record does not exist.
+func (editor *DNSEditor) DeleteRecordContext(ctx context.Context, domain, name string, record TypedRecord) error {
+
+	// validate parameters
+	if err := validateTypedRecordArguments(domain, name, record); err != nil {
+		return err
+	}
+
+	existingRecord, err := editor.findTypedRecord(ctx, domain, name, record)
+	if err != nil {
+		return err
+	}
+
+	deleteError := editor.contextClient().DestroyRecordContext(ctx, domain, existingRecord.StringId())
+	editor.invalidateCache(domain)
+	return deleteError
+}
+
+// findTypedRecord returns the record with the given name that matches the
+// given typed record. A *RecordNotFoundError is returned if there is none.
+func (editor *DNSEditor) findTypedRecord(ctx context.Context, domain, name string, record TypedRecord) (dnsimple.Record, error) {
+	records, err := editor.contextInfoProvider().GetSubdomainRecordsContext(ctx, domain, name)
+	if err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	for _, existingRecord := range records {
+		if recordMatches(existingRecord, record) {
+			return existingRecord, nil
+		}
+	}
+
+	return dnsimple.Record{}, &RecordNotFoundError{domain, name, record.Type()}
+}
+
+// validateTypedRecordArguments returns an error if the given domain, record
+// name or typed record is invalid.
+func validateTypedRecordArguments(domain, name string, record TypedRecord) error {
+	if isValidDomain(domain) == false {
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
+	}
+
+	if isValidRecordName(name) == false {
+		return newKindError(ErrInvalidArgument, "The record name is invalid: %q", name)
+	}
+
+	if record == nil {
+		return newKindError(ErrInvalidArgument, "No record supplied")
+	}
+
+	return record.Validate()
+}
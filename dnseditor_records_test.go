@@ -0,0 +1,255 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"github.com/pearkes/dnsimple"
+	"testing"
+)
+
+// testPriorityDNSClient is a DNS client that implements the PriorityRecordWriter interface.
+type testPriorityDNSClient struct {
+	testDNSClient
+
+	createPriorityRecordFunc func(domain string, opts *dnsimple.ChangeRecord, priority int) (string, error)
+	updatePriorityRecordFunc func(domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error)
+}
+
+func (client *testPriorityDNSClient) CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.createPriorityRecordFunc(domain, opts, priority)
+}
+
+func (client *testPriorityDNSClient) UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return client.updatePriorityRecordFunc(domain, id, opts, priority)
+}
+
+// newTypedRecordTestInfoProvider returns an info provider with the given records.
+func newTypedRecordTestInfoProvider(records ...dnsimple.Record) *testDNSInfoProvider {
+	return &testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return RecordFilter{Name: subdomain}.apply(records), nil
+		},
+	}
+}
+
+// Invalid names and records should be rejected before any request is sent.
+func Test_CreateRecord_ParametersInvalid_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		domain string
+		name   string
+		ttl    int
+		record TypedRecord
+	}{
+		{"", "www", 3600, CNAMERecord{"example.org"}},
+		{"example.com", "-www", 3600, CNAMERecord{"example.org"}},
+		{"example.com", "www", 3600, nil},
+		{"example.com", "www", 3600, CNAMERecord{""}},
+		{"example.com", "www", 0, CNAMERecord{"example.org"}},
+	}
+	editor := DNSEditor{}
+
+	for _, input := range inputs {
+
+		// act
+		_, err := editor.CreateRecord(input.domain, input.name, input.ttl, input.record)
+
+		// assert
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Fail()
+			t.Logf("CreateRecord(%q, %q, %d, %#v) should return ErrInvalidArgument but returned %v", input.domain, input.name, input.ttl, input.record, err)
+		}
+	}
+}
+
+// MX records should be created with their priority.
+func Test_CreateRecord_MXRecord_RecordIsCreatedWithPriority(t *testing.T) {
+	// arrange
+	var createdRecord *dnsimple.ChangeRecord
+	var createdPriority int
+	editor := DNSEditor{
+		client: &testPriorityDNSClient{
+			createPriorityRecordFunc: func(domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+				createdRecord, createdPriority = opts, priority
+				return "42", nil
+			},
+		},
+		infoProvider: newTypedRecordTestInfoProvider(dnsimple.Record{Id: 1, Name: "", RecordType: "MX", Content: "mx1.example.com", Prio: 10}),
+	}
+
+	// act
+	id, err := editor.CreateRecord("example.com", "", 3600, MXRecord{Priority: 20, Host: "mx2.example.com"})
+
+	// assert
+	expected := dnsimple.ChangeRecord{Name: "", Value: "mx2.example.com", Type: "MX", Ttl: "3600"}
+	if err != nil || id != "42" || createdRecord == nil || *createdRecord != expected || createdPriority != 20 {
+		t.Fail()
+		t.Logf("CreateRecord() should create %+v with priority 20 but created %+v with priority %d (%q, %v)", expected, createdRecord, createdPriority, id, err)
+	}
+}
+
+// Priority records cannot be created with clients that do not support priorities.
+func Test_CreateRecord_PriorityNotSupported_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client:       &testDNSClient{},
+		infoProvider: newTypedRecordTestInfoProvider(),
+	}
+
+	// act
+	_, err := editor.CreateRecord("example.com", "_sip._tcp", 3600, SRVRecord{Priority: 10, Weight: 60, Port: 5060, Target: "sip.example.com"})
+
+	// assert
+	if !errors.Is(err, ErrPriorityNotSupported) {
+		t.Fail()
+		t.Logf("CreateRecord() should return ErrPriorityNotSupported but returned %v", err)
+	}
+}
+
+// Existing records should not be created again.
+func Test_CreateRecord_RecordExists_RecordExistsErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client:       &testDNSClient{},
+		infoProvider: newTypedRecordTestInfoProvider(dnsimple.Record{Id: 5, Name: "_acme-challenge", RecordType: "TXT", Content: `"token"`}),
+	}
+
+	// act
+	_, err := editor.CreateRecord("example.com", "_acme-challenge", 60, TXTRecord{"token"})
+
+	// assert
+	var existsError *RecordExistsError
+	if !errors.As(err, &existsError) || existsError.RecordID != "5" {
+		t.Fail()
+		t.Logf("CreateRecord() should return a RecordExistsError for the record 5 but returned %v", err)
+	}
+}
+
+// CNAME records cannot share their name with other records.
+func Test_CreateRecord_CNAMEWithOtherRecords_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client:       &testDNSClient{},
+		infoProvider: newTypedRecordTestInfoProvider(dnsimple.Record{Id: 5, Name: "www", RecordType: "A", Content: "127.0.0.1"}),
+	}
+
+	// act
+	_, err := editor.CreateRecord("example.com", "www", 3600, CNAMERecord{"example.org"})
+
+	// assert
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fail()
+		t.Logf("CreateRecord() should reject the CNAME record but returned %v", err)
+	}
+}
+
+// UpdateRecord should replace the matching record and keep its name and type.
+func Test_UpdateRecord_CAARecord_RecordIsReplaced(t *testing.T) {
+	// arrange
+	var updatedID string
+	var updatedRecord *dnsimple.ChangeRecord
+	editor := DNSEditor{
+		client: &testDNSClient{
+			updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+				updatedID, updatedRecord = id, opts
+				return id, nil
+			},
+		},
+		infoProvider: newTypedRecordTestInfoProvider(
+			dnsimple.Record{Id: 1, Name: "", RecordType: "CAA", Content: `0 issue "letsencrypt.org"`, Ttl: 3600},
+			dnsimple.Record{Id: 2, Name: "", RecordType: "CAA", Content: `0 issue "pki.goog"`, Ttl: 3600},
+		),
+	}
+
+	// act
+	err := editor.UpdateRecord("example.com", "", 3600, CAARecord{0, "issue", "pki.goog"}, CAARecord{0, "issuewild", "pki.goog"})
+
+	// assert
+	expected := dnsimple.ChangeRecord{Name: "", Value: `0 issuewild "pki.goog"`, Type: "CAA", Ttl: "3600"}
+	if err != nil || updatedID != "2" || updatedRecord == nil || *updatedRecord != expected {
+		t.Fail()
+		t.Logf("UpdateRecord() should update the record 2 to %+v but updated %q to %+v (%v)", expected, updatedID, updatedRecord, err)
+	}
+}
+
+// UpdateRecord should return ErrNoUpdateRequired if nothing changes.
+func Test_UpdateRecord_NothingChanged_NoUpdateRequiredErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client:       &testDNSClient{},
+		infoProvider: newTypedRecordTestInfoProvider(dnsimple.Record{Id: 1, Name: "www", RecordType: "CNAME", Content: "example.org", Ttl: 3600}),
+	}
+
+	// act
+	err := editor.UpdateRecord("example.com", "www", 3600, CNAMERecord{"example.org"}, CNAMERecord{"example.org."})
+
+	// assert
+	if !errors.Is(err, ErrNoUpdateRequired) {
+		t.Fail()
+		t.Logf("UpdateRecord() should return ErrNoUpdateRequired but returned %v", err)
+	}
+}
+
+// The type of a record cannot be changed.
+func Test_UpdateRecord_DifferentTypes_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{}
+
+	// act
+	err := editor.UpdateRecord("example.com", "", 3600, NSRecord{"ns1.example.com"}, CNAMERecord{"example.org"})
+
+	// assert
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fail()
+		t.Logf("UpdateRecord() should return ErrInvalidArgument but returned %v", err)
+	}
+}
+
+// DeleteRecord should delete the record with the given content only.
+func Test_DeleteRecord_RecordExists_RecordIsDeleted(t *testing.T) {
+	// arrange
+	var deletedID string
+	editor := DNSEditor{
+		client: &testDNSClient{
+			destroyRecordFunc: func(domain string, id string) error {
+				deletedID = id
+				return nil
+			},
+		},
+		infoProvider: newTypedRecordTestInfoProvider(
+			dnsimple.Record{Id: 1, Name: "", RecordType: "MX", Content: "mx1.example.com", Prio: 10},
+			dnsimple.Record{Id: 2, Name: "", RecordType: "MX", Content: "mx2.example.com", Prio: 20},
+		),
+	}
+
+	// act
+	err := editor.DeleteRecord("example.com", "", MXRecord{20, "mx2.example.com"})
+
+	// assert
+	if err != nil || deletedID != "2" {
+		t.Fail()
+		t.Logf("DeleteRecord() should delete the record 2 but deleted %q (%v)", deletedID, err)
+	}
+}
+
+// DeleteRecord should return ErrNotFound for missing records.
+func Test_DeleteRecord_RecordNotFound_NotFoundErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client:       &testDNSClient{},
+		infoProvider: newTypedRecordTestInfoProvider(dnsimple.Record{Id: 1, Name: "", RecordType: "NS", Content: "ns1.dnsimple.com"}),
+	}
+
+	// act
+	err := editor.DeleteRecord("example.com", "", NSRecord{"ns2.dnsimple.com"})
+
+	// assert
+	if !errors.Is(err, ErrNotFound) {
+		t.Fail()
+		t.Logf("DeleteRecord() should return ErrNotFound but returned %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"net"
+)
+
+// RecordSetChange describes the changes made to an address record set.
+type RecordSetChange struct {
+	// Created contains the IDs of the created records.
+	Created []string
+
+	// Deleted contains the IDs of the deleted records.
+	Deleted []string
+}
+
+// IsEmpty returns true if the record set was not changed.
+func (change RecordSetChange) IsEmpty() bool {
+	return len(change.Created) == 0 && len(change.Deleted) == 0
+}
+
+// ReplaceRecordSet makes the address records of the given type and
+// subdomain point to exactly the given IP addresses (e.g. for round-robin
+// setups). Missing addresses are created with the given time to live,
+// addresses that are not in the list are deleted and the remaining
+// records are not touched. An empty list deletes the whole record set.
+func (editor *DNSEditor) ReplaceRecordSet(domain, subdomain, recordType string, timeToLive int, ips []net.IP) (RecordSetChange, error) {
+	return editor.ReplaceRecordSetContext(context.Background(), domain, subdomain, recordType, timeToLive, ips)
+}
+
+// ReplaceRecordSetContext makes the address records of the given type and
+// subdomain point to exactly the given IP addresses (e.g. for round-robin
+// setups). Missing addresses are created with the given time to live,
+// addresses that are not in the list are deleted and the remaining
+// records are not touched. An empty list deletes the whole record set.
+//
+// New records are created before old ones are deleted so that the name
+// keeps resolving. If a request fails the returned change contains the
+// changes that were made before the error.
+func (editor *DNSEditor) ReplaceRecordSetContext(ctx context.Context, domain, subdomain, recordType string, timeToLive int, ips []net.IP) (RecordSetChange, error) {
+
+	// validate parameters
+	if err := validateRecordSetArguments(domain, subdomain, recordType, timeToLive); err != nil {
+		return RecordSetChange{}, err
+	}
+
+	for _, ip := range ips {
+		if ip == nil || getDNSRecordTypeByIP(ip) != recordType {
+			return RecordSetChange{}, newKindError(ErrInvalidArgument, "The ip %q is not valid for %s records", ip, recordType)
+		}
+	}
+
+	records, err := editor.getRecordSet(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return RecordSetChange{}, err
+	}
+
+	// keep one record per requested address and delete all others
+	var requestedIPs, missingIPs []net.IP
+	kept := make(map[int64]bool)
+	for _, ip := range ips {
+		if containsIP(requestedIPs, ip) {
+			continue
+		}
+
+		requestedIPs = append(requestedIPs, ip)
+		if index := findAddressRecord(records, ip); index >= 0 {
+			kept[records[index].Id] = true
+		} else {
+			missingIPs = append(missingIPs, ip)
+		}
+	}
+
+	var obsoleteRecords []dnsimple.Record
+	for _, record := range records {
+		if !kept[record.Id] {
+			obsoleteRecords = append(obsoleteRecords, record)
+		}
+	}
+
+	return editor.changeRecordSet(ctx, domain, subdomain, recordType, timeToLive, missingIPs, obsoleteRecords)
+}
+
+// AddToRecordSet adds the given IP address to the address record set of
+// the given subdomain. The change is empty if the address exists already.
+func (editor *DNSEditor) AddToRecordSet(domain, subdomain string, timeToLive int, ip net.IP) (RecordSetChange, error) {
+	return editor.AddToRecordSetContext(context.Background(), domain, subdomain, timeToLive, ip)
+}
+
+// AddToRecordSetContext adds the given IP address to the address record set
+// of the given subdomain. The change is empty if the address exists already.
+func (editor *DNSEditor) AddToRecordSetContext(ctx context.Context, domain, subdomain string, timeToLive int, ip net.IP) (RecordSetChange, error) {
+	if ip == nil {
+		return RecordSetChange{}, newKindError(ErrInvalidArgument, "No ip supplied")
+	}
+
+	recordType := getDNSRecordTypeByIP(ip)
+	if err := validateRecordSetArguments(domain, subdomain, recordType, timeToLive); err != nil {
+		return RecordSetChange{}, err
+	}
+
+	records, err := editor.getRecordSet(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return RecordSetChange{}, err
+	}
+
+	if findAddressRecord(records, ip) >= 0 {
+		return RecordSetChange{}, nil
+	}
+
+	return editor.changeRecordSet(ctx, domain, subdomain, recordType, timeToLive, []net.IP{ip}, nil)
+}
+
+// RemoveFromRecordSet removes the given IP address from the address record
+// set of the given subdomain. The change is empty if the address does not exist.
+func (editor *DNSEditor) RemoveFromRecordSet(domain, subdomain string, ip net.IP) (RecordSetChange, error) {
+	return editor.RemoveFromRecordSetContext(context.Background(), domain, subdomain, ip)
+}
+
+// RemoveFromRecordSetContext removes the given IP address from the address
+// record set of the given subdomain. The change is empty if the address
+// does not exist. Duplicate records of the address are removed as well.
+func (editor *DNSEditor) RemoveFromRecordSetContext(ctx context.Context, domain, subdomain string, ip net.IP) (RecordSetChange, error) {
+	if ip == nil {
+		return RecordSetChange{}, newKindError(ErrInvalidArgument, "No ip supplied")
+	}
+
+	recordType := getDNSRecordTypeByIP(ip)
+	if err := validateRecordSetArguments(domain, subdomain, recordType, MinTTL); err != nil {
+		return RecordSetChange{}, err
+	}
+
+	records, err := editor.getRecordSet(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return RecordSetChange{}, err
+	}
+
+	var obsoleteRecords []dnsimple.Record
+	for _, record := range records {
+		if ip.Equal(net.ParseIP(record.Content)) {
+			obsoleteRecords = append(obsoleteRecords, record)
+		}
+	}
+
+	return editor.changeRecordSet(ctx, domain, subdomain, recordType, MinTTL, nil, obsoleteRecords)
+}
+
+// getRecordSet returns all address records of the given type and subdomain.
+func (editor *DNSEditor) getRecordSet(ctx context.Context, domain, subdomain, recordType string) ([]dnsimple.Record, error) {
+	records, err := editor.contextInfoProvider().GetSubdomainRecordsContext(ctx, domain, subdomain)
+	if isRecordNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return RecordFilter{Name: subdomain, Type: recordType}.apply(records), nil
+}
+
+// getAddressRecord returns the only address record of the given type
+// and subdomain. An error matching ErrNotFound is returned if there is
+// none and an error matching ErrMultipleRecords if there are several,
+// so that round-robin setups are not changed at random.
+func (editor *DNSEditor) getAddressRecord(ctx context.Context, domain, subdomain, recordType string) (dnsimple.Record, error) {
+	records, err := editor.getRecordSet(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	switch len(records) {
+	case 0:
+		return dnsimple.Record{}, &RecordNotFoundError{domain, subdomain, recordType}
+
+	case 1:
+		return records[0], nil
+	}
+
+	return dnsimple.Record{}, newKindError(ErrMultipleRecords, "%q has %d %s records. Use ReplaceRecordSet to change all of them.", subdomain, len(records), recordType)
+}
+
+// changeRecordSet creates address records for the given IP addresses and
+// then deletes the given obsolete records.
+func (editor *DNSEditor) changeRecordSet(ctx context.Context, domain, subdomain, recordType string, timeToLive int, ips []net.IP, obsoleteRecords []dnsimple.Record) (RecordSetChange, error) {
+	var change RecordSetChange
+	for _, ip := range ips {
+		id, err := editor.createAddressRecord(ctx, domain, subdomain, recordType, timeToLive, ip)
+		if err != nil {
+			return change, err
+		}
+
+		change.Created = append(change.Created, id)
+	}
+
+	for _, record := range obsoleteRecords {
+		err := editor.contextClient().DestroyRecordContext(ctx, domain, record.StringId())
+		editor.invalidateCache(domain)
+		if err != nil {
+			return change, err
+		}
+
+		change.Deleted = append(change.Deleted, record.StringId())
+	}
+
+	return change, nil
+}
+
+// findAddressRecord returns the index of the first record that points
+// to the given IP address or -1 if there is none.
+func findAddressRecord(records []dnsimple.Record, ip net.IP) int {
+	for index, record := range records {
+		if ip.Equal(net.ParseIP(record.Content)) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// containsIP returns true if the given list contains the given IP address.
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, existingIP := range ips {
+		if existingIP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateRecordSetArguments returns an error if the given domain,
+// subdomain, record type or time to live is invalid.
+func validateRecordSetArguments(domain, subdomain, recordType string, timeToLive int) error {
+	if isValidDomain(domain) == false {
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
+	}
+
+	if recordType != "A" && recordType != "AAAA" {
+		return newKindError(ErrInvalidArgument, "Record sets are only supported for A and AAAA records: %q", recordType)
+	}
+
+	return validateTTL(timeToLive)
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// newRecordSetTestEditor returns an editor for the given records that
+// records the values of created records and the IDs of deleted records.
+func newRecordSetTestEditor(created, deleted *[]string, records ...dnsimple.Record) DNSEditor {
+	return DNSEditor{
+		client: &testDNSClient{
+			createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+				*created = append(*created, opts.Value)
+				return fmt.Sprintf("%d", 100+len(*created)), nil
+			},
+			destroyRecordFunc: func(domain string, id string) error {
+				*deleted = append(*deleted, id)
+				return nil
+			},
+		},
+		infoProvider: newTypedRecordTestInfoProvider(records...),
+	}
+}
+
+// roundRobinRecords is a record set with three A records, a duplicate and an AAAA record.
+var roundRobinRecords = []dnsimple.Record{
+	{Id: 1, Name: "www", RecordType: "A", Content: "10.0.0.1", Ttl: 600},
+	{Id: 2, Name: "www", RecordType: "A", Content: "10.0.0.2", Ttl: 600},
+	{Id: 3, Name: "www", RecordType: "A", Content: "10.0.0.3", Ttl: 600},
+	{Id: 4, Name: "www", RecordType: "A", Content: "10.0.0.1", Ttl: 600},
+	{Id: 5, Name: "www", RecordType: "AAAA", Content: "::1", Ttl: 600},
+}
+
+// ReplaceRecordSet should only create the missing and delete the obsolete records.
+func Test_ReplaceRecordSet_MinimalChangesAreMade(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords...)
+	ips := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.4"), net.ParseIP("10.0.0.4")}
+
+	// act
+	change, err := editor.ReplaceRecordSet("example.com", "www", "A", 600, ips)
+
+	// assert
+	if err != nil || !reflect.DeepEqual(created, []string{"10.0.0.4"}) || !reflect.DeepEqual(deleted, []string{"2", "4"}) {
+		t.Fail()
+		t.Logf("ReplaceRecordSet() should create 10.0.0.4 and delete the records 2 and 4 but created %v and deleted %v (%v)", created, deleted, err)
+	}
+
+	if !reflect.DeepEqual(change, RecordSetChange{Created: []string{"101"}, Deleted: []string{"2", "4"}}) {
+		t.Fail()
+		t.Logf("ReplaceRecordSet() returned an unexpected change: %+v", change)
+	}
+}
+
+// ReplaceRecordSet should not send any request if the set is complete.
+func Test_ReplaceRecordSet_SetUnchanged_NoRequestsAreSent(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords[:3]...)
+	ips := []net.IP{net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1")}
+
+	// act
+	change, err := editor.ReplaceRecordSet("example.com", "www", "A", 600, ips)
+
+	// assert
+	if err != nil || !change.IsEmpty() || len(created) != 0 || len(deleted) != 0 {
+		t.Fail()
+		t.Logf("ReplaceRecordSet() should not change anything but returned %+v, %v", change, err)
+	}
+}
+
+// ReplaceRecordSet with an empty list should delete the record set of the given type only.
+func Test_ReplaceRecordSet_EmptyList_RecordSetIsDeleted(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords...)
+
+	// act
+	_, err := editor.ReplaceRecordSet("example.com", "www", "AAAA", 600, nil)
+
+	// assert
+	if err != nil || len(created) != 0 || !reflect.DeepEqual(deleted, []string{"5"}) {
+		t.Fail()
+		t.Logf("ReplaceRecordSet() should delete the AAAA record but deleted %v (%v)", deleted, err)
+	}
+}
+
+// ReplaceRecordSet should reject addresses of the wrong family.
+func Test_ReplaceRecordSet_WrongAddressFamily_ErrorIsReturned(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords...)
+
+	// act
+	_, err := editor.ReplaceRecordSet("example.com", "www", "A", 600, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::2")})
+
+	// assert
+	if !errors.Is(err, ErrInvalidArgument) || len(created) != 0 || len(deleted) != 0 {
+		t.Fail()
+		t.Logf("ReplaceRecordSet() should return ErrInvalidArgument but returned %v", err)
+	}
+}
+
+// AddToRecordSet should only create addresses that do not exist.
+func Test_AddToRecordSet(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords...)
+
+	// act
+	existingChange, existingError := editor.AddToRecordSet("example.com", "www", 600, net.ParseIP("10.0.0.2"))
+	newChange, newError := editor.AddToRecordSet("example.com", "www", 600, net.ParseIP("10.0.0.5"))
+
+	// assert
+	if existingError != nil || !existingChange.IsEmpty() {
+		t.Fail()
+		t.Logf("AddToRecordSet() should not add an existing address but returned %+v, %v", existingChange, existingError)
+	}
+
+	if newError != nil || !reflect.DeepEqual(created, []string{"10.0.0.5"}) || len(newChange.Created) != 1 {
+		t.Fail()
+		t.Logf("AddToRecordSet() should add the new address but created %v (%+v, %v)", created, newChange, newError)
+	}
+}
+
+// RemoveFromRecordSet should delete all records of the address and nothing else.
+func Test_RemoveFromRecordSet(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords...)
+
+	// act
+	change, err := editor.RemoveFromRecordSet("example.com", "www", net.ParseIP("10.0.0.1"))
+	missingChange, missingError := editor.RemoveFromRecordSet("example.com", "www", net.ParseIP("10.0.0.9"))
+
+	// assert
+	if err != nil || !reflect.DeepEqual(change.Deleted, []string{"1", "4"}) || !reflect.DeepEqual(deleted, []string{"1", "4"}) {
+		t.Fail()
+		t.Logf("RemoveFromRecordSet() should delete the records 1 and 4 but deleted %v (%+v, %v)", deleted, change, err)
+	}
+
+	if missingError != nil || !missingChange.IsEmpty() {
+		t.Fail()
+		t.Logf("RemoveFromRecordSet() should ignore missing addresses but returned %+v, %v", missingChange, missingError)
+	}
+}
+
+// Failed requests should be reported together with the changes made so far.
+func Test_ReplaceRecordSet_DestroyFails_PartialChangeIsReturned(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords[:2]...)
+	editor.client.(*testDNSClient).destroyRecordFunc = func(domain string, id string) error {
+		return newKindError(ErrServer, "Server error")
+	}
+
+	// act
+	change, err := editor.ReplaceRecordSet("example.com", "www", "A", 600, []net.IP{net.ParseIP("10.0.0.9")})
+
+	// assert
+	if !errors.Is(err, ErrServer) || len(change.Created) != 1 || len(change.Deleted) != 0 {
+		t.Fail()
+		t.Logf("ReplaceRecordSet() should return the created record and the error but returned %+v, %v", change, err)
+	}
+}
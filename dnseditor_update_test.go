@@ -5,6 +5,8 @@
 package deens
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 	"net"
@@ -57,8 +59,8 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainNotFound_ErrorIsReturned(t *t
 	ip := net.ParseIP("::1")
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, fmt.Errorf("")
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return nil, fmt.Errorf("")
 		},
 	}
 
@@ -89,8 +91,8 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainExists_DNSRecordUpdateFails_E
 	}
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, nil
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: subdomain, RecordType: "AAAA", Content: "::2"}}, nil
 		},
 	}
 
@@ -122,8 +124,8 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainExists_DNSRecordUpdateSucceed
 	}
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return dnsimple.Record{}, nil
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: subdomain, RecordType: "AAAA", Content: "::2"}}, nil
 		},
 	}
 
@@ -156,15 +158,15 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainExists_ExistingIPIsTheSame_Er
 	}
 
 	existingRecord := dnsimple.Record{
-		Name:       "example.com",
+		Name:       "www",
 		Content:    "::1",
 		RecordType: "AAAA",
 		Ttl:        600,
 	}
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return existingRecord, nil
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{existingRecord}, nil
 		},
 	}
 
@@ -190,7 +192,7 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainExists_OnlyTheIPIsChangedOnTh
 	ip := net.ParseIP("::2")
 
 	existingRecord := dnsimple.Record{
-		Name:       "example.com",
+		Name:       "www",
 		Content:    "::1",
 		RecordType: "AAAA",
 		Ttl:        600,
@@ -212,7 +214,7 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainExists_OnlyTheIPIsChangedOnTh
 
 			if opts.Ttl != fmt.Sprintf("%d", existingRecord.Ttl) {
 				t.Fail()
-				t.Logf("The DNS record TTL should not change during an update (Old: %q, New: %q)", existingRecord.Ttl, opts.Ttl)
+				t.Logf("The DNS record TTL should not change during an update (Old: %d, New: %q)", existingRecord.Ttl, opts.Ttl)
 			}
 
 			if opts.Value != ip.String() {
@@ -225,8 +227,8 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainExists_OnlyTheIPIsChangedOnTh
 	}
 
 	infoProvider := &testDNSInfoProvider{
-		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
-			return existingRecord, nil
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{existingRecord}, nil
 		},
 	}
 
@@ -238,3 +240,214 @@ func Test_UpdateSubdomain_ValidParameters_SubdomainExists_OnlyTheIPIsChangedOnTh
 	// act
 	editor.UpdateSubdomain(domain, subdomain, ip)
 }
+
+// UpdateSubdomainContext should pass the context on to the DNS client.
+func Test_UpdateSubdomainContext_ContextIsPassedToDNSClient(t *testing.T) {
+	// arrange
+	type contextKey string
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+
+	var receivedContext context.Context
+	dnsClient := &testContextDNSClient{
+		testDNSClient: testDNSClient{
+			getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+				return []dnsimple.Record{dnsimple.Record{Id: 1, Name: "www", RecordType: "AAAA", Content: "::2"}}, nil
+			},
+		},
+		updateRecordContextFunc: func(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+			receivedContext = ctx
+			return id, nil
+		},
+	}
+
+	editor := DNSEditor{
+		client:       dnsClient,
+		infoProvider: NewDNSInfoProvider(dnsClient),
+	}
+
+	// act
+	err := editor.UpdateSubdomainContext(ctx, "example.com", "www", net.ParseIP("::1"))
+
+	// assert
+	if err != nil || receivedContext == nil || receivedContext.Value(contextKey("key")) != "value" {
+		t.Fail()
+		t.Logf("UpdateSubdomainContext() should pass the given context to the DNS client (error: %v)", err)
+	}
+}
+
+// newTTLTestEditor returns an editor with an existing www record that
+// passes every update to the given function.
+func newTTLTestEditor(existingRecord dnsimple.Record, update func(opts *dnsimple.ChangeRecord)) DNSEditor {
+	return DNSEditor{
+		client: &testDNSClient{
+			updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+				update(opts)
+				return id, nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return []dnsimple.Record{existingRecord}, nil
+			},
+		},
+	}
+}
+
+// UpdateSubdomainWithTTL should change the TTL even if the IP did not change.
+func Test_UpdateSubdomainWithTTL_OnlyTTLChanged_RecordIsUpdated(t *testing.T) {
+	// arrange
+	var updatedRecord *dnsimple.ChangeRecord
+	editor := newTTLTestEditor(dnsimple.Record{Id: 1, Name: "www", RecordType: "A", Content: "127.0.0.1", Ttl: 3600}, func(opts *dnsimple.ChangeRecord) {
+		updatedRecord = opts
+	})
+
+	// act
+	err := editor.UpdateSubdomainWithTTL("example.com", "www", 60, net.ParseIP("127.0.0.1"))
+
+	// assert
+	expected := dnsimple.ChangeRecord{Name: "www", Value: "127.0.0.1", Type: "A", Ttl: "60"}
+	if err != nil || updatedRecord == nil || *updatedRecord != expected {
+		t.Fail()
+		t.Logf("UpdateSubdomainWithTTL() should update the record to %+v but sent %+v (%v)", expected, updatedRecord, err)
+	}
+}
+
+// UpdateSubdomainWithTTL should return ErrNoUpdateRequired if neither the IP nor the TTL changed.
+func Test_UpdateSubdomainWithTTL_NothingChanged_NoUpdateRequiredErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := newTTLTestEditor(dnsimple.Record{Id: 1, Name: "www", RecordType: "AAAA", Content: "::1", Ttl: 600}, func(opts *dnsimple.ChangeRecord) {
+		t.Fail()
+		t.Logf("UpdateRecord() should not be called if nothing changed")
+	})
+
+	// act
+	err := editor.UpdateSubdomainWithTTL("example.com", "www", 600, net.ParseIP("::1"))
+
+	// assert
+	if !errors.Is(err, ErrNoUpdateRequired) {
+		t.Fail()
+		t.Logf("UpdateSubdomainWithTTL() should return ErrNoUpdateRequired but returned %v", err)
+	}
+}
+
+// Addresses should be compared as IPs and not as strings.
+func Test_UpdateSubdomain_NonCanonicalIPv6Stored_NoUpdateRequiredErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := newTTLTestEditor(dnsimple.Record{Id: 1, Name: "www", RecordType: "AAAA", Content: "2001:DB8::0001", Ttl: 600}, func(opts *dnsimple.ChangeRecord) {
+		t.Fail()
+		t.Logf("UpdateRecord() should not be called if nothing changed")
+	})
+
+	// act
+	updateError := editor.UpdateSubdomain("example.com", "www", net.ParseIP("2001:db8::1"))
+	updateWithTTLError := editor.UpdateSubdomainWithTTL("example.com", "www", 600, net.ParseIP("2001:db8::1"))
+
+	// assert
+	if !errors.Is(updateError, ErrNoUpdateRequired) || !errors.Is(updateWithTTLError, ErrNoUpdateRequired) {
+		t.Fail()
+		t.Logf("The updates should return ErrNoUpdateRequired but returned %v, %v", updateError, updateWithTTLError)
+	}
+}
+
+// UpdateSubdomainWithTTL should reject TTLs outside of the valid range.
+func Test_UpdateSubdomainWithTTL_InvalidTTL_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{}
+
+	// act
+	err := editor.UpdateSubdomainWithTTL("example.com", "www", MinTTL-1, net.ParseIP("::1"))
+
+	// assert
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fail()
+		t.Logf("UpdateSubdomainWithTTL() should return ErrInvalidArgument but returned %v", err)
+	}
+}
+
+// UpdateSubdomainTTL should change the TTL and keep the IP address.
+func Test_UpdateSubdomainTTL_TTLChanged_ContentIsKept(t *testing.T) {
+	// arrange
+	var updatedRecord *dnsimple.ChangeRecord
+	editor := newTTLTestEditor(dnsimple.Record{Id: 1, Name: "www", RecordType: "AAAA", Content: "::1", Ttl: 60}, func(opts *dnsimple.ChangeRecord) {
+		updatedRecord = opts
+	})
+
+	// act
+	err := editor.UpdateSubdomainTTL("example.com", "www", "AAAA", 3600)
+
+	// assert
+	expected := dnsimple.ChangeRecord{Name: "www", Value: "::1", Type: "AAAA", Ttl: "3600"}
+	if err != nil || updatedRecord == nil || *updatedRecord != expected {
+		t.Fail()
+		t.Logf("UpdateSubdomainTTL() should update the record to %+v but sent %+v (%v)", expected, updatedRecord, err)
+	}
+}
+
+// UpdateSubdomainTTL should return ErrNoUpdateRequired if the TTL did not change.
+func Test_UpdateSubdomainTTL_TTLUnchanged_NoUpdateRequiredErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := newTTLTestEditor(dnsimple.Record{Id: 1, Name: "www", RecordType: "A", Content: "127.0.0.1", Ttl: 600}, func(opts *dnsimple.ChangeRecord) {
+		t.Fail()
+		t.Logf("UpdateRecord() should not be called if the TTL did not change")
+	})
+
+	// act
+	err := editor.UpdateSubdomainTTL("example.com", "www", "A", 600)
+
+	// assert
+	if !errors.Is(err, ErrNoUpdateRequired) {
+		t.Fail()
+		t.Logf("UpdateSubdomainTTL() should return ErrNoUpdateRequired but returned %v", err)
+	}
+}
+
+// UpdateSubdomainTTL only supports address records.
+func Test_UpdateSubdomainTTL_InvalidRecordType_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{}
+
+	// act
+	err := editor.UpdateSubdomainTTL("example.com", "www", "MX", 600)
+
+	// assert
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fail()
+		t.Logf("UpdateSubdomainTTL() should return ErrInvalidArgument but returned %v", err)
+	}
+}
+
+// Updates should not pick an arbitrary record if several records match.
+func Test_UpdateSubdomain_MultipleRecords_ErrorIsReturned(t *testing.T) {
+	// arrange
+	var created, deleted []string
+	editor := newRecordSetTestEditor(&created, &deleted, roundRobinRecords...)
+
+	// act
+	updateError := editor.UpdateSubdomain("example.com", "www", net.ParseIP("10.0.0.9"))
+	updateWithTTLError := editor.UpdateSubdomainWithTTL("example.com", "www", 600, net.ParseIP("10.0.0.9"))
+	updateTTLError := editor.UpdateSubdomainTTL("example.com", "www", "A", 3600)
+
+	// assert
+	for _, err := range []error{updateError, updateWithTTLError, updateTTLError} {
+		if !errors.Is(err, ErrMultipleRecords) {
+			t.Fail()
+			t.Logf("Updates of the www A records should return ErrMultipleRecords but returned %v", err)
+		}
+	}
+}
+
+// The editor returned by NewDNSEditor should offer the TTL functions by type assertion.
+func Test_NewDNSEditor_ImplementsTTLUpdater(t *testing.T) {
+	// arrange
+	editor := NewDNSEditor(&testDNSClient{}, &testDNSInfoProvider{})
+
+	// act
+	_, ok := editor.(DNSRecordTTLUpdater)
+	_, contextOK := editor.(ContextDNSRecordTTLUpdater)
+
+	// assert
+	if !ok || !contextOK {
+		t.Fail()
+		t.Logf("NewDNSEditor() should return an editor that implements DNSRecordTTLUpdater and ContextDNSRecordTTLUpdater")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"net"
+)
+
+// UpsertAction describes what UpsertSubdomain did.
+type UpsertAction string
+
+const (
+	// UpsertCreated means that a new record was created.
+	UpsertCreated UpsertAction = "created"
+
+	// UpsertUpdated means that the IP address of an existing record was changed.
+	UpsertUpdated UpsertAction = "updated"
+
+	// UpsertUnchanged means that the record pointed to the IP address already.
+	UpsertUnchanged UpsertAction = "unchanged"
+)
+
+// UpsertResult is the result of UpsertSubdomain.
+type UpsertResult struct {
+	// Action is the action that was taken.
+	Action UpsertAction
+
+	// RecordID is the ID of the created, updated or unchanged record.
+	RecordID string
+}
+
+// UpsertSubdomain makes the address record of the given subdomain point
+// to the given IP address. The record is created with the given time to
+// live if it does not exist; existing records keep their TTL. No request
+// is sent if the record has the given IP address already.
+func (editor *DNSEditor) UpsertSubdomain(domain, subdomain string, timeToLive int, ip net.IP) (UpsertResult, error) {
+	return editor.UpsertSubdomainContext(context.Background(), domain, subdomain, timeToLive, ip)
+}
+
+// UpsertSubdomainContext makes the address record of the given subdomain point
+// to the given IP address. The record is created with the given time to
+// live if it does not exist; existing records keep their TTL. No request
+// is sent if the record has the given IP address already. An error matching
+// ErrMultipleRecords is returned if the subdomain has several address records
+// of the same type (see ReplaceRecordSet).
+func (editor *DNSEditor) UpsertSubdomainContext(ctx context.Context, domain, subdomain string, timeToLive int, ip net.IP) (UpsertResult, error) {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return UpsertResult{}, newKindError(ErrInvalidArgument, "The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return UpsertResult{}, newKindError(ErrInvalidArgument, "The domain name is invalid: %q", subdomain)
+	}
+
+	if err := validateTTL(timeToLive); err != nil {
+		return UpsertResult{}, err
+	}
+
+	if ip == nil {
+		return UpsertResult{}, newKindError(ErrInvalidArgument, "No ip supplied")
+	}
+
+	// get the existing records
+	recordType := getDNSRecordTypeByIP(ip)
+	records, err := editor.getRecordSet(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+
+	switch len(records) {
+	case 0:
+		id, createError := editor.createAddressRecord(ctx, domain, subdomain, recordType, timeToLive, ip)
+		if createError != nil {
+			return UpsertResult{}, createError
+		}
+
+		return UpsertResult{UpsertCreated, id}, nil
+
+	case 1:
+		// handled below
+
+	default:
+		return UpsertResult{}, newKindError(ErrMultipleRecords, "%q has %d %s records. Use ReplaceRecordSet to change all of them.", subdomain, len(records), recordType)
+	}
+
+	// update the record if the IP address changed
+	subdomainRecord := records[0]
+	if ip.Equal(net.ParseIP(subdomainRecord.Content)) {
+		return UpsertResult{UpsertUnchanged, subdomainRecord.StringId()}, nil
+	}
+
+	if err := editor.updateAddressRecord(ctx, domain, subdomainRecord, ip.String(), subdomainRecord.Ttl); err != nil {
+		return UpsertResult{}, err
+	}
+
+	return UpsertResult{UpsertUpdated, subdomainRecord.StringId()}, nil
+}
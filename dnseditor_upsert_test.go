@@ -0,0 +1,261 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"testing"
+)
+
+// If any of the given parameters is invalid UpsertSubdomain should respond with an error.
+func Test_UpsertSubdomain_ParametersInvalid_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		domain    string
+		subdomain string
+		ttl       int
+		ip        net.IP
+	}{
+		{"", "www", 600, net.ParseIP("::1")},
+		{"example.com", "", 600, net.ParseIP("::1")},
+		{"example.com", "www", 600, nil},
+		{"example.com", "www", 0, net.ParseIP("::1")},
+	}
+	editor := DNSEditor{}
+
+	for _, input := range inputs {
+
+		// act
+		_, err := editor.UpsertSubdomain(input.domain, input.subdomain, input.ttl, input.ip)
+
+		// assert
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Fail()
+			t.Logf("UpsertSubdomain(%q, %q, %d, %q) should return an invalid argument error but returned %v.", input.domain, input.subdomain, input.ttl, input.ip, err)
+		}
+	}
+}
+
+// UpsertSubdomain should create missing records with the given TTL.
+func Test_UpsertSubdomain_SubdomainNotFound_RecordIsCreated(t *testing.T) {
+	// arrange
+	var createdRecord *dnsimple.ChangeRecord
+	editor := DNSEditor{
+		client: &testDNSClient{
+			createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+				createdRecord = opts
+				return "42", nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return nil, nil
+			},
+		},
+	}
+
+	// act
+	result, err := editor.UpsertSubdomain("example.com", "home", 300, net.ParseIP("127.0.0.1"))
+
+	// assert
+	if err != nil || result != (UpsertResult{UpsertCreated, "42"}) {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should create the record 42 but returned %+v, %v", result, err)
+	}
+
+	expected := dnsimple.ChangeRecord{Name: "home", Value: "127.0.0.1", Type: "A", Ttl: "300"}
+	if createdRecord == nil || *createdRecord != expected {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should create the record %+v but created %+v", expected, createdRecord)
+	}
+}
+
+// UpsertSubdomain should update existing records and keep their TTL.
+func Test_UpsertSubdomain_IPChanged_RecordIsUpdated(t *testing.T) {
+	// arrange
+	var updatedRecord *dnsimple.ChangeRecord
+	editor := DNSEditor{
+		client: &testDNSClient{
+			updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+				updatedRecord = opts
+				return id, nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return []dnsimple.Record{{Id: 7, Name: subdomain, RecordType: "A", Content: "127.0.0.1", Ttl: 3600}}, nil
+			},
+		},
+	}
+
+	// act
+	result, err := editor.UpsertSubdomain("example.com", "home", 300, net.ParseIP("127.0.0.2"))
+
+	// assert
+	if err != nil || result != (UpsertResult{UpsertUpdated, "7"}) {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should update the record 7 but returned %+v, %v", result, err)
+	}
+
+	expected := dnsimple.ChangeRecord{Name: "home", Value: "127.0.0.2", Type: "A", Ttl: "3600"}
+	if updatedRecord == nil || *updatedRecord != expected {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should update the record to %+v but sent %+v", expected, updatedRecord)
+	}
+}
+
+// UpsertSubdomain should not change records that point to the given IP already.
+func Test_UpsertSubdomain_IPUnchanged_NothingIsChanged(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client: &testDNSClient{
+			updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+				t.Fail()
+				t.Logf("UpdateRecord() should not be called if the IP did not change")
+				return id, nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return []dnsimple.Record{{Id: 7, Name: subdomain, RecordType: "AAAA", Content: "::1", Ttl: 3600}}, nil
+			},
+		},
+	}
+
+	// act
+	result, err := editor.UpsertSubdomain("example.com", "home", 300, net.ParseIP("::1"))
+
+	// assert
+	if err != nil || result != (UpsertResult{UpsertUnchanged, "7"}) {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should leave the record 7 unchanged but returned %+v, %v", result, err)
+	}
+}
+
+// Addresses should be compared as IPs and not as strings.
+func Test_UpsertSubdomain_NonCanonicalIPv6Stored_NothingIsChanged(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client: &testDNSClient{
+			updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+				t.Fail()
+				t.Logf("UpdateRecord() should not be called if the IP did not change")
+				return id, nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return []dnsimple.Record{{Id: 7, Name: subdomain, RecordType: "AAAA", Content: "2001:DB8::0001", Ttl: 3600}}, nil
+			},
+		},
+	}
+
+	// act
+	result, err := editor.UpsertSubdomain("example.com", "home", 300, net.ParseIP("2001:db8::1"))
+
+	// assert
+	if err != nil || result != (UpsertResult{UpsertUnchanged, "7"}) {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should leave the record 7 unchanged but returned %+v, %v", result, err)
+	}
+}
+
+// UpsertSubdomain should return lookup errors that are not caused by a missing record.
+func Test_UpsertSubdomain_LookupFails_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client: &testDNSClient{},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return nil, fmt.Errorf("Connection reset")
+			},
+		},
+	}
+
+	// act
+	_, err := editor.UpsertSubdomain("example.com", "home", 300, net.ParseIP("::1"))
+
+	// assert
+	if err == nil || err.Error() != "Connection reset" {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should return the lookup error but returned %v", err)
+	}
+}
+
+// Info providers that report missing records with a plain ErrNotFound should lead to a new record.
+func Test_UpsertSubdomain_InfoProviderReturnsErrNotFound_RecordIsCreated(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client: &testDNSClient{
+			createRecordFunc: func(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+				return "42", nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return nil, ErrNotFound
+			},
+		},
+	}
+
+	// act
+	result, err := editor.UpsertSubdomain("example.com", "home", 300, net.ParseIP("::1"))
+
+	// assert
+	if err != nil || result != (UpsertResult{UpsertCreated, "42"}) {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should create the record but returned %+v, %v", result, err)
+	}
+}
+
+// UpsertSubdomain should not pick an arbitrary record if several records match.
+func Test_UpsertSubdomain_MultipleRecords_ErrorIsReturned(t *testing.T) {
+	// arrange
+	editor := DNSEditor{
+		client: &testDNSClient{
+			updateRecordFunc: func(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+				t.Fail()
+				t.Logf("UpdateRecord() should not be called for ambiguous records")
+				return id, nil
+			},
+		},
+		infoProvider: &testDNSInfoProvider{
+			getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+				return []dnsimple.Record{
+					{Id: 7, Name: subdomain, RecordType: "A", Content: "127.0.0.1", Ttl: 3600},
+					{Id: 8, Name: subdomain, RecordType: "A", Content: "127.0.0.2", Ttl: 3600},
+					{Id: 9, Name: subdomain, RecordType: "AAAA", Content: "::1", Ttl: 3600},
+				}, nil
+			},
+		},
+	}
+
+	// act
+	_, err := editor.UpsertSubdomain("example.com", "home", 300, net.ParseIP("127.0.0.3"))
+
+	// assert
+	if !errors.Is(err, ErrMultipleRecords) {
+		t.Fail()
+		t.Logf("UpsertSubdomain() should return ErrMultipleRecords but returned %v", err)
+	}
+}
+
+// The editor returned by NewDNSEditor should offer UpsertSubdomainContext as a ContextDNSRecordEditor.
+func Test_NewDNSEditor_ImplementsContextDNSRecordEditor(t *testing.T) {
+	// arrange
+	editor := NewDNSEditor(&testDNSClient{}, &testDNSInfoProvider{})
+
+	// act
+	_, ok := editor.(ContextDNSRecordEditor)
+
+	// assert
+	if !ok {
+		t.Fail()
+		t.Logf("NewDNSEditor() should return an editor that implements ContextDNSRecordEditor")
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is matched by errors that report a missing domain or record.
+var ErrNotFound = errors.New("Not found")
+
+// ErrAuthentication is matched by errors that report rejected credentials
+// or missing permissions.
+var ErrAuthentication = errors.New("Authentication failed")
+
+// ErrValidation is matched by errors that report invalid request
+// parameters (400 and 422 responses).
+var ErrValidation = errors.New("Validation failed")
+
+// ErrRateLimited is matched by errors that report an exceeded API quota.
+var ErrRateLimited = errors.New("Rate limit exceeded")
+
+// ErrServer is matched by errors that report a server-side failure (5xx).
+var ErrServer = errors.New("Server error")
+
+// ErrInvalidArgument is matched by errors that report invalid arguments
+// that were rejected before a request was sent.
+var ErrInvalidArgument = errors.New("Invalid argument")
+
+// ErrNoUpdateRequired is matched by errors that report an update that
+// would not change anything.
+var ErrNoUpdateRequired = errors.New("No update required")
+
+// ErrAlreadyExists is matched by errors that report a record that
+// cannot be created because it exists already.
+var ErrAlreadyExists = errors.New("Already exists")
+
+// ErrMultipleRecords is matched by errors that report several records
+// where only one was expected (e.g. round-robin address records).
+var ErrMultipleRecords = errors.New("Multiple records found")
+
+// APIError is returned if the DNSimple API responds with an error status.
+// Use errors.Is with ErrNotFound, ErrAuthentication, ErrValidation,
+// ErrRateLimited or ErrServer to check the kind of failure.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Status is the HTTP status line of the response (e.g. "404 Not Found").
+	Status string
+
+	// Message contains the error details returned by the API.
+	Message string
+
+	// Errors contains the validation errors returned by the API
+	// grouped by parameter name.
+	Errors map[string][]string
+
+	// Domain is the name of the domain the request targeted.
+	Domain string
+
+	// RecordID is the ID of the record the request targeted.
+	RecordID string
+
+	// RetryAfter is the delay requested by the API with the
+	// Retry-After header. It is zero if the header was not set.
+	RetryAfter time.Duration
+}
+
+func (err *APIError) Error() string {
+	message := err.Status
+	if !isEmpty(err.Message) {
+		message = err.Message
+	}
+
+	if len(err.Errors) > 0 {
+		message = fmt.Sprintf("%s (%s)", message, joinValidationErrors(err.Errors))
+	}
+
+	return fmt.Sprintf("API Error: %s", message)
+}
+
+// Is reports whether the given target matches the kind of this error.
+func (err *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return err.StatusCode == http.StatusNotFound
+	case ErrAuthentication:
+		return err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden
+	case ErrValidation:
+		return err.StatusCode == http.StatusBadRequest || err.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return err.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return err.StatusCode >= 500
+	}
+
+	return false
+}
+
+// Temporary returns true if the request might succeed if it is sent again.
+func (err *APIError) Temporary() bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+}
+
+// RecordNotFoundError is returned if no record matches the given
+// subdomain and record type. It matches ErrNotFound.
+type RecordNotFoundError struct {
+	// Domain is the name of the domain that was searched.
+	Domain string
+
+	// Subdomain is the name of the record that was not found.
+	Subdomain string
+
+	// RecordType is the type of the record that was not found.
+	RecordType string
+}
+
+func (err *RecordNotFoundError) Error() string {
+	return fmt.Sprintf("No record found for %s.%s", err.Subdomain, err.Domain)
+}
+
+// Is reports whether the given target is ErrNotFound.
+func (err *RecordNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// isRecordNotFound returns true if the given error reports a missing
+// record. Info providers may return a *RecordNotFoundError or any other
+// error matching ErrNotFound; a 404 response of the API means that the
+// domain itself does not exist and does not count as a missing record.
+func isRecordNotFound(err error) bool {
+	var apiError *APIError
+	return errors.Is(err, ErrNotFound) && !errors.As(err, &apiError)
+}
+
+// RecordExistsError is returned if a record with the given subdomain
+// and record type exists already. It matches ErrAlreadyExists.
+type RecordExistsError struct {
+	// Domain is the name of the domain that contains the record.
+	Domain string
+
+	// Subdomain is the name of the existing record.
+	Subdomain string
+
+	// RecordType is the type of the existing record.
+	RecordType string
+
+	// RecordID is the ID of the existing record.
+	RecordID string
+}
+
+func (err *RecordExistsError) Error() string {
+	return fmt.Sprintf("A record of type %q exists already for %s.%s (ID: %s)", err.RecordType, err.Subdomain, err.Domain, err.RecordID)
+}
+
+// Is reports whether the given target is ErrAlreadyExists.
+func (err *RecordExistsError) Is(target error) bool {
+	return target == ErrAlreadyExists
+}
+
+// kindError is an error with a custom message that matches one of the
+// sentinel errors of this package.
+type kindError struct {
+	message string
+	kind    error
+}
+
+// newKindError returns an error with the given message that matches
+// the given kind with errors.Is.
+func newKindError(kind error, format string, args ...interface{}) error {
+	return &kindError{fmt.Sprintf(format, args...), kind}
+}
+
+func (err *kindError) Error() string {
+	return err.message
+}
+
+// Unwrap returns the kind of this error.
+func (err *kindError) Unwrap() error {
+	return err.kind
+}
+
+// annotateAPIError adds the given domain and record ID to the APIError
+// contained in the given error.
+func annotateAPIError(err error, domain, recordID string) error {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		apiError.Domain = domain
+		apiError.RecordID = recordID
+	}
+
+	return err
+}
+
+// joinValidationErrors formats the given validation errors in a stable order.
+func joinValidationErrors(validationErrors map[string][]string) string {
+	var fields []string
+	for field := range validationErrors {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	var messages []string
+	for _, field := range fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field, strings.Join(validationErrors[field], ", ")))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// parseRetryAfter returns the delay of the given Retry-After header
+// value. The value can either be a number of seconds or an HTTP date.
+// Returns zero if the value is empty or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if isEmpty(value) {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
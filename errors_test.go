@@ -0,0 +1,130 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// APIErrors should match the sentinel error of their status code.
+func Test_APIError_Is_StatusCodeMatchesSentinel(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		statusCode int
+		sentinel   error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusUnauthorized, ErrAuthentication},
+		{http.StatusForbidden, ErrAuthentication},
+		{http.StatusBadRequest, ErrValidation},
+		{http.StatusUnprocessableEntity, ErrValidation},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusInternalServerError, ErrServer},
+		{http.StatusBadGateway, ErrServer},
+	}
+
+	for _, input := range inputs {
+		// act
+		err := fmt.Errorf("Error creating record: %w", &APIError{StatusCode: input.statusCode})
+
+		// assert
+		if !errors.Is(err, input.sentinel) {
+			t.Fail()
+			t.Logf("An APIError with status %d should match %q", input.statusCode, input.sentinel)
+		}
+
+		if input.sentinel != ErrNotFound && errors.Is(err, ErrNotFound) {
+			t.Fail()
+			t.Logf("An APIError with status %d must not match %q", input.statusCode, ErrNotFound)
+		}
+	}
+}
+
+// Validation errors should contain the messages returned by the API, the domain and the record ID.
+func Test_v1Client_ValidationFails_APIErrorContainsDetails(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprint(w, `{"message": "Validation failed", "errors": {"content": ["can't be blank"]}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	_, err := client.UpdateRecord("example.com", "42", &dnsimple.ChangeRecord{Name: "www"})
+
+	// assert
+	var apiError *APIError
+	if !errors.As(err, &apiError) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("UpdateRecord() should return a validation APIError but returned %v", err)
+	}
+
+	if apiError.Domain != "example.com" || apiError.RecordID != "42" || len(apiError.Errors["content"]) != 1 {
+		t.Fail()
+		t.Logf("The APIError should contain the domain, the record ID and the validation errors but was %+v", apiError)
+	}
+}
+
+// GetSubdomainRecord should return a RecordNotFoundError if no record matches.
+func Test_GetSubdomainRecord_NoMatchingRecord_RecordNotFoundErrorIsReturned(t *testing.T) {
+	// arrange
+	dnsClient := &testDNSClient{
+		getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{dnsimple.Record{Name: "www", RecordType: "A"}}, nil
+		},
+	}
+
+	infoProvider := dnsimpleInfoProvider{dnsClient}
+
+	// act
+	_, err := infoProvider.GetSubdomainRecord("example.com", "www", "AAAA")
+
+	// assert
+	var notFoundError *RecordNotFoundError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &notFoundError) || notFoundError.RecordType != "AAAA" {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return a RecordNotFoundError but returned %v", err)
+	}
+}
+
+// The editor should return typed errors for invalid arguments, missing records and unnecessary updates.
+func Test_UpdateSubdomain_ErrorsAreTyped(t *testing.T) {
+	// arrange
+	dnsClient := &testDNSClient{
+		getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{dnsimple.Record{Name: "www", RecordType: "A", Content: "127.0.0.1"}}, nil
+		},
+	}
+
+	editor := DNSEditor{dnsClient, NewDNSInfoProvider(dnsClient)}
+
+	// act
+	invalidArgumentError := editor.UpdateSubdomain("", "www", net.ParseIP("127.0.0.1"))
+	notFoundError := editor.UpdateSubdomain("example.com", "mail", net.ParseIP("127.0.0.1"))
+	noUpdateError := editor.UpdateSubdomain("example.com", "www", net.ParseIP("127.0.0.1"))
+
+	// assert
+	if !errors.Is(invalidArgumentError, ErrInvalidArgument) {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should return ErrInvalidArgument for an empty domain but returned %v", invalidArgumentError)
+	}
+
+	if !errors.Is(notFoundError, ErrNotFound) {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should return ErrNotFound for a missing record but returned %v", notFoundError)
+	}
+
+	if !errors.Is(noUpdateError, ErrNoUpdateRequired) {
+		t.Fail()
+		t.Logf("UpdateSubdomain() should return ErrNoUpdateRequired if the IP did not change but returned %v", noUpdateError)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+)
+
+// RecordFilter selects the records of a domain by name and type.
+type RecordFilter struct {
+	// Name is the exact name of the records. The empty name matches
+	// the records of the domain itself.
+	Name string
+
+	// Type is the record type (e.g. "A"). The empty type matches all types.
+	Type string
+}
+
+// Matches returns true if the given record passes the filter.
+func (filter RecordFilter) Matches(record dnsimple.Record) bool {
+	if record.Name != filter.Name {
+		return false
+	}
+
+	return filter.Type == "" || record.RecordType == filter.Type
+}
+
+// apply returns the given records that pass the filter.
+func (filter RecordFilter) apply(records []dnsimple.Record) []dnsimple.Record {
+	var filteredRecords []dnsimple.Record
+	for _, record := range records {
+		if !filter.Matches(record) {
+			continue
+		}
+
+		filteredRecords = append(filteredRecords, record)
+	}
+
+	return filteredRecords
+}
+
+// RecordFilterer is implemented by DNS clients that can let the API
+// filter the records of a domain instead of downloading all records.
+type RecordFilterer interface {
+	// GetFilteredRecordsContext returns the DNS records of the given
+	// domain that match the given filter.
+	GetFilteredRecordsContext(ctx context.Context, domain string, filter RecordFilter) ([]dnsimple.Record, error)
+}
+
+// getFilteredRecords returns the DNS records of the given domain that
+// match the given filter. Clients that do not implement the
+// RecordFilterer interface are filtered locally.
+func getFilteredRecords(ctx context.Context, client ContextDNSClient, domain string, filter RecordFilter) ([]dnsimple.Record, error) {
+	if filterer, ok := client.(RecordFilterer); ok {
+		return filterer.GetFilteredRecordsContext(ctx, domain, filter)
+	}
+
+	records, err := client.GetRecordsContext(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.apply(records), nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_RecordFilter_Matches(t *testing.T) {
+	inputs := []struct {
+		filter  RecordFilter
+		record  dnsimple.Record
+		matches bool
+	}{
+		{RecordFilter{"www", "A"}, dnsimple.Record{Name: "www", RecordType: "A"}, true},
+		{RecordFilter{"www", ""}, dnsimple.Record{Name: "www", RecordType: "AAAA"}, true},
+		{RecordFilter{"www", "A"}, dnsimple.Record{Name: "www", RecordType: "AAAA"}, false},
+		{RecordFilter{"www", "A"}, dnsimple.Record{Name: "mail", RecordType: "A"}, false},
+		{RecordFilter{"", "MX"}, dnsimple.Record{Name: "", RecordType: "MX"}, true},
+		{RecordFilter{"", ""}, dnsimple.Record{Name: "www", RecordType: "A"}, false},
+	}
+
+	for _, input := range inputs {
+		// act
+		matches := input.filter.Matches(input.record)
+
+		// assert
+		if matches != input.matches {
+			t.Fail()
+			t.Logf("%+v.Matches(%+v) should return %t", input.filter, input.record, input.matches)
+		}
+	}
+}
+
+// The info provider should let the API v2 filter the records by name and type.
+func Test_GetSubdomainRecord_FiltererClient_FilterIsSentToAPI(t *testing.T) {
+	// arrange
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if r.URL.Query().Get("name") != "www" || r.URL.Query().Get("type") != "A" {
+			fmt.Fprint(w, `{"data": []}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"data": [{"id": 42, "name": "www", "type": "A", "content": "127.0.0.1", "ttl": 600}]}`)
+	}))
+	defer server.Close()
+
+	infoProvider := NewDNSInfoProvider(newTestV2Client("1010", server))
+
+	// act
+	record, err := infoProvider.GetSubdomainRecord("example.com", "www", "A")
+
+	// assert
+	if err != nil || record.Id != 42 {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return the record filtered by the API but returned %v, %v (query: %s)", record, err, query)
+	}
+}
+
+// Records returned by the API that do not match the filter should be removed.
+func Test_v2Client_GetFilteredRecords_APIIgnoresFilter_RecordsAreFilteredLocally(t *testing.T) {
+	// arrange
+	var nameSent bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, nameSent = r.URL.Query()["name"]
+		fmt.Fprint(w, `{"data": [{"id": 1, "name": "", "type": "MX", "content": "mx.example.com"}, {"id": 2, "name": "www", "type": "A", "content": "127.0.0.1"}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	records, err := client.GetFilteredRecordsContext(context.Background(), "example.com", RecordFilter{Name: ""})
+
+	// assert
+	if err != nil || len(records) != 1 || records[0].Id != 1 {
+		t.Fail()
+		t.Logf("GetFilteredRecordsContext() should return only the apex record but returned %v, %v", records, err)
+	}
+
+	if nameSent {
+		t.Fail()
+		t.Logf("GetFilteredRecordsContext() should not send an empty name")
+	}
+}
+
+// Decorators should pass the filter to the wrapped client.
+func Test_NewRetryingDNSClient_FiltererClient_FilterIsForwarded(t *testing.T) {
+	// arrange
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("name") + "/" + r.URL.Query().Get("type")
+		fmt.Fprint(w, `{"data": []}`)
+	}))
+	defer server.Close()
+
+	client := NewRetryingDNSClient(NewRateLimitedDNSClient(newTestV2Client("1010", server), DefaultRateLimitPolicy()), DefaultRetryPolicy())
+
+	// act
+	NewDNSInfoProvider(client).GetSubdomainRecords("example.com", "www")
+
+	// assert
+	if query != "www/" {
+		t.Fail()
+		t.Logf("The name filter should have been sent to the API but the query was %q", query)
+	}
+}
@@ -5,7 +5,7 @@
 package deens
 
 import (
-	"fmt"
+	"context"
 	"github.com/pearkes/dnsimple"
 )
 
@@ -22,7 +22,9 @@ type DNSInfoProvider interface {
 	GetDomainRecords(domain string) ([]dnsimple.Record, error)
 
 	// GetSubdomainRecord returns the DNS record for the given domain, subdomain and record type.
-	// Returns an error if no DNS record was found.
+	// Returns an error matching ErrNotFound if no DNS record was found. If several records match
+	// only the first one is returned; use GetSubdomainRecords or the DNSRecordSetEditor
+	// for round-robin record sets.
 	GetSubdomainRecord(domain, subdomain, recordType string) (dnsimple.Record, error)
 
 	// GetSubdomainRecords returns a list of all available DNS records for the
@@ -30,11 +32,46 @@ type DNSInfoProvider interface {
 	GetSubdomainRecords(domain, subdomain string) ([]dnsimple.Record, error)
 }
 
+// The ContextDNSInfoProvider interface offers DNS info functions
+// that can be cancelled with a context.
+type ContextDNSInfoProvider interface {
+
+	// GetDomainNamesContext returns a list of domain names.
+	// Returns an error if the domain names cannot be fetched.
+	GetDomainNamesContext(ctx context.Context) ([]string, error)
+
+	// GetDomainRecordsContext returns all DNS records for the given domain.
+	// Returns an error of the DNS records cannot be fetched or the
+	// given domain was not found.
+	GetDomainRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error)
+
+	// GetSubdomainRecordContext returns the DNS record for the given domain, subdomain and record type.
+	// Returns an error if no DNS record was found.
+	GetSubdomainRecordContext(ctx context.Context, domain, subdomain, recordType string) (dnsimple.Record, error)
+
+	// GetSubdomainRecordsContext returns a list of all available DNS records for the
+	// given domain and subdomain.
+	GetSubdomainRecordsContext(ctx context.Context, domain, subdomain string) ([]dnsimple.Record, error)
+}
+
 // NewDNSInfoProvider creates a new DNS info provider instance.
+// The returned provider also implements the ContextDNSInfoProvider interface.
 func NewDNSInfoProvider(client DNSClient) DNSInfoProvider {
 	return &dnsimpleInfoProvider{client}
 }
 
+// AsContextDNSInfoProvider returns the given info provider as a
+// ContextDNSInfoProvider. Providers that do not support contexts
+// natively are wrapped in an adapter that checks the context before
+// each call but cannot abort calls that are already in progress.
+func AsContextDNSInfoProvider(infoProvider DNSInfoProvider) ContextDNSInfoProvider {
+	if contextInfoProvider, ok := infoProvider.(ContextDNSInfoProvider); ok {
+		return contextInfoProvider
+	}
+
+	return &contextDNSInfoProviderAdapter{infoProvider}
+}
+
 // dnsimpleInfoProvider returns DNS records from the DNSimple API.
 type dnsimpleInfoProvider struct {
 	client DNSClient
@@ -42,8 +79,30 @@ type dnsimpleInfoProvider struct {
 
 // GetDomainNames returns a list of all available domain names.
 func (infoProvider *dnsimpleInfoProvider) GetDomainNames() ([]string, error) {
+	return infoProvider.GetDomainNamesContext(context.Background())
+}
+
+// GetDomainRecords returns all DNS records for the given domain.
+func (infoProvider *dnsimpleInfoProvider) GetDomainRecords(domain string) ([]dnsimple.Record, error) {
+	return infoProvider.GetDomainRecordsContext(context.Background(), domain)
+}
+
+// GetSubdomainRecord return the subdomain record that matches the given name and record type.
+// If no matching subdomain was found or an error occurred while fetching the available records
+// an error will be returned.
+func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecord(domain, subdomain, recordType string) (dnsimple.Record, error) {
+	return infoProvider.GetSubdomainRecordContext(context.Background(), domain, subdomain, recordType)
+}
 
-	domains, err := infoProvider.client.GetDomains()
+// GetSubdomainRecords returns all DNS records for the given subdomain.
+func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecords(domain, subdomain string) ([]dnsimple.Record, error) {
+	return infoProvider.GetSubdomainRecordsContext(context.Background(), domain, subdomain)
+}
+
+// GetDomainNamesContext returns a list of all available domain names.
+func (infoProvider *dnsimpleInfoProvider) GetDomainNamesContext(ctx context.Context) ([]string, error) {
+
+	domains, err := AsContextDNSClient(infoProvider.client).GetDomainsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -56,24 +115,18 @@ func (infoProvider *dnsimpleInfoProvider) GetDomainNames() ([]string, error) {
 	return domainNames, nil
 }
 
-// GetDomainRecords returns all DNS records for the given domain.
-func (infoProvider *dnsimpleInfoProvider) GetDomainRecords(domain string) ([]dnsimple.Record, error) {
-
-	return infoProvider.getDNSRecords(domain, func(record dnsimple.Record) bool {
-		return true
-	})
-
+// GetDomainRecordsContext returns all DNS records for the given domain.
+func (infoProvider *dnsimpleInfoProvider) GetDomainRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return AsContextDNSClient(infoProvider.client).GetRecordsContext(ctx, domain)
 }
 
-// GetSubdomainRecord return the subdomain record that matches the given name and record type.
+// GetSubdomainRecordContext return the subdomain record that matches the given name and record type.
 // If no matching subdomain was found or an error occurred while fetching the available records
 // an error will be returned.
-func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecord(domain, subdomain, recordType string) (dnsimple.Record, error) {
+func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecordContext(ctx context.Context, domain, subdomain, recordType string) (dnsimple.Record, error) {
 
 	// get all records that have matching subdomain name and record type
-	records, err := infoProvider.getDNSRecords(domain, func(record dnsimple.Record) bool {
-		return record.Name == subdomain && record.RecordType == recordType
-	})
+	records, err := infoProvider.getDNSRecords(ctx, domain, RecordFilter{Name: subdomain, Type: recordType})
 
 	// error while fetching DNS records
 	if err != nil {
@@ -82,39 +135,62 @@ func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecord(domain, subdomain,
 
 	// no records found
 	if len(records) == 0 {
-		return dnsimple.Record{}, fmt.Errorf("No record found for %s.%s", subdomain, domain)
+		return dnsimple.Record{}, &RecordNotFoundError{domain, subdomain, recordType}
 	}
 
 	// return the first record found
 	return records[0], nil
 }
 
-// GetSubdomainRecords returns all DNS records for the given subdomain.
-func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecords(domain, subdomain string) ([]dnsimple.Record, error) {
+// GetSubdomainRecordsContext returns all DNS records for the given subdomain.
+func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecordsContext(ctx context.Context, domain, subdomain string) ([]dnsimple.Record, error) {
+	return infoProvider.getDNSRecords(ctx, domain, RecordFilter{Name: subdomain})
+}
 
-	return infoProvider.getDNSRecords(domain, func(record dnsimple.Record) bool {
-		return record.Name == subdomain
-	})
+// getDNSRecords returns all DNS records for the given domain that pass the given filter.
+// The filter is passed to the API if the client implements the RecordFilterer interface;
+// otherwise all records are fetched and filtered locally.
+func (infoProvider *dnsimpleInfoProvider) getDNSRecords(ctx context.Context, domain string, filter RecordFilter) ([]dnsimple.Record, error) {
+	return getFilteredRecords(ctx, AsContextDNSClient(infoProvider.client), domain, filter)
+}
 
+// contextDNSInfoProviderAdapter adds context support to a DNSInfoProvider.
+type contextDNSInfoProviderAdapter struct {
+	infoProvider DNSInfoProvider
 }
 
-// getDNSRecords returns all DNS records for the given domain that pass the given filter expression.
-func (infoProvider *dnsimpleInfoProvider) getDNSRecords(domain string, includeInResult func(record dnsimple.Record) bool) ([]dnsimple.Record, error) {
+// GetDomainNamesContext returns a list of domain names.
+func (adapter *contextDNSInfoProviderAdapter) GetDomainNamesContext(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	// get all DNS records for the given domain
-	records, err := infoProvider.client.GetRecords(domain)
-	if err != nil {
+	return adapter.infoProvider.GetDomainNames()
+}
+
+// GetDomainRecordsContext returns all DNS records for the given domain.
+func (adapter *contextDNSInfoProviderAdapter) GetDomainRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	var filteredRecords []dnsimple.Record
-	for _, record := range records {
-		if !includeInResult(record) {
-			continue
-		}
+	return adapter.infoProvider.GetDomainRecords(domain)
+}
 
-		filteredRecords = append(filteredRecords, record)
+// GetSubdomainRecordContext returns the DNS record for the given domain, subdomain and record type.
+func (adapter *contextDNSInfoProviderAdapter) GetSubdomainRecordContext(ctx context.Context, domain, subdomain, recordType string) (dnsimple.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	return adapter.infoProvider.GetSubdomainRecord(domain, subdomain, recordType)
+}
+
+// GetSubdomainRecordsContext returns all DNS records for the given domain and subdomain.
+func (adapter *contextDNSInfoProviderAdapter) GetSubdomainRecordsContext(ctx context.Context, domain, subdomain string) ([]dnsimple.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	return filteredRecords, nil
+	return adapter.infoProvider.GetSubdomainRecords(domain, subdomain)
 }
@@ -0,0 +1,128 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-cleanhttp"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the base URL of the DNSimple API v1.
+const DefaultBaseURL = "https://api.dnsimple.com/v1"
+
+// SandboxBaseURL is the base URL of the DNSimple sandbox API v1.
+const SandboxBaseURL = "https://api.sandbox.dnsimple.com/v1"
+
+// DefaultV2BaseURL is the base URL of the DNSimple API v2.
+const DefaultV2BaseURL = "https://api.dnsimple.com/v2"
+
+// SandboxV2BaseURL is the base URL of the DNSimple sandbox API v2.
+const SandboxV2BaseURL = "https://api.sandbox.dnsimple.com/v2"
+
+// DefaultUserAgent is the user agent that is sent with every request
+// unless it is replaced with WithUserAgent.
+const DefaultUserAgent = "dee-ns (+https://github.com/andreaskoch/dee-ns)"
+
+// ClientOption configures the DNS clients created by NewDNSClient.
+type ClientOption func(options *clientOptions)
+
+// clientOptions contains the settings of a DNS client.
+type clientOptions struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+	baseURL    string
+	userAgent  string
+	timeout    time.Duration
+}
+
+// WithHTTPClient sets the HTTP client that is used for all requests.
+// The given client is not modified by other options.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(options *clientOptions) {
+		options.httpClient = client
+	}
+}
+
+// WithTransport sets the RoundTripper that is used for all requests
+// (e.g. to use a proxy or a custom CA).
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(options *clientOptions) {
+		options.transport = transport
+	}
+}
+
+// WithBaseURL sets the base URL of the API (e.g. SandboxBaseURL or the
+// URL of a local test server). The base URL must match the API version
+// of the credentials: AccessTokenCredentials use the API v2, all other
+// credentials the API v1.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(options *clientOptions) {
+		options.baseURL = baseURL
+	}
+}
+
+// WithUserAgent sets the User-Agent header that is sent with every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(options *clientOptions) {
+		options.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets the time limit for each request including
+// connecting, redirects and reading the response body.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(options *clientOptions) {
+		options.timeout = timeout
+	}
+}
+
+// newClientOptions applies the given options to the default settings
+// and validates the result.
+func newClientOptions(defaultBaseURL string, options ...ClientOption) (clientOptions, error) {
+	settings := clientOptions{
+		baseURL:   defaultBaseURL,
+		userAgent: DefaultUserAgent,
+	}
+
+	for _, option := range options {
+		option(&settings)
+	}
+
+	baseURL, err := url.Parse(settings.baseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return clientOptions{}, fmt.Errorf("The base URL is invalid: %q", settings.baseURL)
+	}
+
+	if settings.timeout < 0 {
+		return clientOptions{}, fmt.Errorf("The timeout must not be negative: %s", settings.timeout)
+	}
+
+	settings.baseURL = strings.TrimSuffix(settings.baseURL, "/")
+	return settings, nil
+}
+
+// newHTTPClient returns the HTTP client for the given settings.
+func (settings clientOptions) newHTTPClient() *http.Client {
+	var client http.Client
+	if settings.httpClient != nil {
+		client = *settings.httpClient
+	} else {
+		client = *cleanhttp.DefaultClient()
+	}
+
+	if settings.transport != nil {
+		client.Transport = settings.transport
+	}
+
+	if settings.timeout > 0 {
+		client.Timeout = settings.timeout
+	}
+
+	return &client
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testRoundTripper is a http.RoundTripper that records the requests it receives.
+type testRoundTripper struct {
+	requests  []*http.Request
+	transport http.RoundTripper
+}
+
+func (roundTripper *testRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	roundTripper.requests = append(roundTripper.requests, request)
+	return roundTripper.transport.RoundTrip(request)
+}
+
+// WithBaseURL should send all requests to the given base URL.
+func Test_NewDNSClient_WithBaseURL_RequestsAreSentToBaseURL(t *testing.T) {
+	// arrange
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		fmt.Fprint(w, `[{"domain": {"id": 1, "name": "example.com"}}]`)
+	}))
+	defer server.Close()
+
+	client, err := NewDNSClient(APICredentials{"john.doe@example.com", "ApItOken"}, WithBaseURL(server.URL+"/v1/"))
+	if err != nil {
+		t.Fatalf("NewDNSClient() returned an error: %s", err.Error())
+	}
+
+	// act
+	domains, err := client.GetDomains()
+
+	// assert
+	if err != nil || len(domains) != 1 {
+		t.Fatalf("GetDomains() should return the domain of the test server but returned %v, %v", domains, err)
+	}
+
+	if path != "/v1/domains" {
+		t.Fail()
+		t.Logf("The request should have been sent to %q but was sent to %q", "/v1/domains", path)
+	}
+}
+
+// The default user agent should be sent unless it is replaced with WithUserAgent.
+func Test_NewDNSClient_UserAgent_UserAgentHeaderIsSent(t *testing.T) {
+	inputs := []struct {
+		options   []ClientOption
+		userAgent string
+	}{
+		{nil, DefaultUserAgent},
+		{[]ClientOption{WithUserAgent("my-dyndns/1.0")}, "my-dyndns/1.0"},
+	}
+
+	for _, input := range inputs {
+		// arrange
+		var userAgent string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userAgent = r.Header.Get("User-Agent")
+			fmt.Fprint(w, `[]`)
+		}))
+
+		options := append([]ClientOption{WithBaseURL(server.URL)}, input.options...)
+		client, _ := NewDNSClient(APICredentials{"john.doe@example.com", "ApItOken"}, options...)
+
+		// act
+		client.GetDomains()
+		server.Close()
+
+		// assert
+		if userAgent != input.userAgent {
+			t.Fail()
+			t.Logf("The User-Agent header should be %q but was %q", input.userAgent, userAgent)
+		}
+	}
+}
+
+// WithTransport should send all requests through the given transport.
+func Test_NewDNSClient_WithTransport_TransportIsUsed(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	transport := &testRoundTripper{transport: http.DefaultTransport}
+	client, _ := NewDNSClient(APICredentials{"john.doe@example.com", "ApItOken"}, WithBaseURL(server.URL), WithTransport(transport))
+
+	// act
+	client.GetDomains()
+
+	// assert
+	if len(transport.requests) != 1 {
+		t.Fail()
+		t.Logf("The transport should have received one request but received %d", len(transport.requests))
+	}
+}
+
+// WithTimeout and WithTransport should not modify the HTTP client passed to WithHTTPClient.
+func Test_newClientOptions_WithHTTPClient_GivenClientIsNotModified(t *testing.T) {
+	// arrange
+	httpClient := &http.Client{}
+	settings, _ := newClientOptions(DefaultBaseURL, WithHTTPClient(httpClient), WithTimeout(time.Minute), WithTransport(&testRoundTripper{}))
+
+	// act
+	result := settings.newHTTPClient()
+
+	// assert
+	if result.Timeout != time.Minute || result.Transport == nil {
+		t.Fail()
+		t.Logf("The timeout and the transport should have been applied to the returned client but it was %+v", result)
+	}
+
+	if httpClient.Timeout != 0 || httpClient.Transport != nil {
+		t.Fail()
+		t.Logf("The given HTTP client should not have been modified but it was %+v", httpClient)
+	}
+}
+
+// Invalid base URLs and negative timeouts should be rejected.
+func Test_NewDNSClient_InvalidOptions_ErrorIsReturned(t *testing.T) {
+	inputs := []ClientOption{
+		WithBaseURL(""),
+		WithBaseURL("api.dnsimple.com/v1"),
+		WithBaseURL("ftp://api.dnsimple.com/v1"),
+		WithBaseURL("https://"),
+		WithTimeout(-time.Second),
+	}
+
+	for _, input := range inputs {
+		// act
+		_, err := NewDNSClient(APICredentials{"john.doe@example.com", "ApItOken"}, input)
+
+		// assert
+		if err == nil {
+			t.Fail()
+			t.Logf("NewDNSClient() should return an error for invalid options")
+		}
+	}
+}
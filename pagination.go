@@ -0,0 +1,185 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"strings"
+)
+
+// RecordPage contains one page of the records of a domain.
+type RecordPage struct {
+	// Records contains the records of the page.
+	Records []dnsimple.Record
+
+	// Next identifies the next page. It is empty on the last page.
+	Next string
+}
+
+// RecordPager is implemented by DNS clients that can return the records
+// of a domain page by page. The clients returned by NewDNSClient,
+// NewRetryingDNSClient and NewRateLimitedDNSClient implement it.
+type RecordPager interface {
+	// GetRecordsPage returns the page of records of the given domain
+	// that is identified by the given page. An empty page identifies
+	// the first page.
+	GetRecordsPage(ctx context.Context, domain, page string) (RecordPage, error)
+}
+
+// NewRecordIterator creates an iterator over all records of the given
+// domain. Clients that implement the RecordPager interface are read
+// page by page so that only one page is held in memory; all other
+// clients are read with a single GetRecords call.
+//
+//	iterator := NewRecordIterator(ctx, client, "example.com")
+//	for iterator.Next() {
+//		record := iterator.Record()
+//		...
+//	}
+//
+//	if err := iterator.Err(); err != nil {
+//		...
+//	}
+func NewRecordIterator(ctx context.Context, client DNSClient, domain string) *RecordIterator {
+	return &RecordIterator{
+		ctx:    ctx,
+		client: AsContextDNSClient(client),
+		domain: domain,
+	}
+}
+
+// RecordIterator iterates over the records of a domain.
+type RecordIterator struct {
+	ctx    context.Context
+	client ContextDNSClient
+	domain string
+
+	records []dnsimple.Record
+	index   int
+	next    string
+	started bool
+	visited map[string]bool
+
+	record dnsimple.Record
+	err    error
+}
+
+// Next advances the iterator to the next record. It returns false when
+// all records have been read or an error occurred.
+func (iterator *RecordIterator) Next() bool {
+	for iterator.index >= len(iterator.records) {
+		if iterator.err != nil || (iterator.started && iterator.next == "") {
+			return false
+		}
+
+		if iterator.visited == nil {
+			iterator.visited = make(map[string]bool)
+		}
+
+		iterator.visited[iterator.next] = true
+		page, err := getRecordsPage(iterator.ctx, iterator.client, iterator.domain, iterator.next)
+		if err == nil && page.Next != "" && iterator.visited[page.Next] {
+			err = fmt.Errorf("The API returned the page %q twice", page.Next)
+		}
+
+		if err != nil {
+			iterator.err = err
+			return false
+		}
+
+		iterator.started = true
+		iterator.records = page.Records
+		iterator.index = 0
+		iterator.next = page.Next
+	}
+
+	iterator.record = iterator.records[iterator.index]
+	iterator.index++
+	return true
+}
+
+// Record returns the current record.
+func (iterator *RecordIterator) Record() dnsimple.Record {
+	return iterator.record
+}
+
+// Err returns the error that stopped the iteration (if any).
+func (iterator *RecordIterator) Err() error {
+	return iterator.err
+}
+
+// getRecordsPage returns the given page of records of the given domain.
+// Clients that do not implement the RecordPager interface return all
+// records on the first page.
+func getRecordsPage(ctx context.Context, client ContextDNSClient, domain, page string) (RecordPage, error) {
+	if pager, ok := client.(RecordPager); ok {
+		return pager.GetRecordsPage(ctx, domain, page)
+	}
+
+	if page != "" {
+		return RecordPage{}, fmt.Errorf("The DNS client does not support pagination")
+	}
+
+	records, err := client.GetRecordsContext(ctx, domain)
+	if err != nil {
+		return RecordPage{}, err
+	}
+
+	return RecordPage{Records: records}, nil
+}
+
+// collectRecords reads all pages of records of the given domain.
+func collectRecords(ctx context.Context, pager RecordPager, domain string) ([]dnsimple.Record, error) {
+	var records []dnsimple.Record
+	visited := make(map[string]bool)
+	page := ""
+	for {
+		visited[page] = true
+		result, err := pager.GetRecordsPage(ctx, domain, page)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, result.Records...)
+		if result.Next == "" {
+			return records, nil
+		}
+
+		if visited[result.Next] {
+			return nil, fmt.Errorf("The API returned the page %q twice", result.Next)
+		}
+
+		page = result.Next
+	}
+}
+
+// parseNextPageLink returns the URL of the link with the relation
+// "next" in the given Link header (RFC 8288).
+func parseNextPageLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, parameter := range parts[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(parameter), "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "rel") {
+				continue
+			}
+
+			for _, relation := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+				if strings.EqualFold(relation, "next") {
+					return target[1 : len(target)-1]
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,304 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// testV1PagedServer creates a test server that returns one record of the
+// domain example.com per page and links to the next page. The number of
+// requests is counted in the given counter.
+func testV1PagedServer(pages int, requests *int) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/domains/example.com/records" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		if page < pages {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/domains/example.com/records?page=%d>; rel="next", <%s/domains/example.com/records?page=%d>; rel="last"`, server.URL, page+1, server.URL, pages))
+		}
+
+		fmt.Fprintf(w, `[{"record": {"id": %d, "name": "www%d", "record_type": "A", "content": "127.0.0.1", "ttl": 600}}]`, page, page)
+	}))
+
+	return server
+}
+
+// parseNextPageLink should return the URL of the next relation only.
+func Test_parseNextPageLink(t *testing.T) {
+	inputs := []struct {
+		header string
+		link   string
+	}{
+		{``, ``},
+		{`<https://api.dnsimple.com/v1/domains?page=2>; rel="next"`, `https://api.dnsimple.com/v1/domains?page=2`},
+		{`<https://example.com/?page=1>; rel="prev", <https://example.com/?page=3>; rel=next`, `https://example.com/?page=3`},
+		{`<https://example.com/?page=5>; rel="last"`, ``},
+		{`https://example.com/?page=2; rel="next"`, ``},
+	}
+
+	for _, input := range inputs {
+		// act
+		link := parseNextPageLink(input.header)
+
+		// assert
+		if link != input.link {
+			t.Fail()
+			t.Logf("parseNextPageLink(%q) should return %q but returned %q", input.header, input.link, link)
+		}
+	}
+}
+
+// GetRecords should follow the next page links until all records are collected.
+func Test_v1Client_GetRecords_MultiplePages_AllRecordsAreReturned(t *testing.T) {
+	// arrange
+	requests := 0
+	server := testV1PagedServer(3, &requests)
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	records, err := client.GetRecords("example.com")
+
+	// assert
+	if err != nil || len(records) != 3 || records[2].Name != "www3" {
+		t.Fail()
+		t.Logf("GetRecords() should return the records of all three pages but returned %v, %v", records, err)
+	}
+}
+
+// Records on later pages should be found by the info provider.
+func Test_GetSubdomainRecord_RecordOnLaterPage_RecordIsReturned(t *testing.T) {
+	// arrange
+	requests := 0
+	server := testV1PagedServer(3, &requests)
+	defer server.Close()
+
+	infoProvider := NewDNSInfoProvider(newTestV1Client(server))
+
+	// act
+	record, err := infoProvider.GetSubdomainRecord("example.com", "www2", "A")
+
+	// assert
+	if err != nil || record.Id != 2 {
+		t.Fail()
+		t.Logf("GetSubdomainRecord() should return the record of the second page but returned %v, %v", record, err)
+	}
+}
+
+// Next page links that point to other hosts must not be followed.
+func Test_v1Client_GetRecords_ForeignNextPageLink_ErrorIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<https://attacker.example.org/domains/example.com/records?page=2>; rel="next"`)
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	_, err := client.GetRecords("example.com")
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("GetRecords() should return an error for next page links outside of the API")
+	}
+}
+
+// The v2 client should request pages until the last page is reached.
+func Test_v2Client_GetRecords_MultiplePages_AllRecordsAreReturned(t *testing.T) {
+	// arrange
+	var requestedPages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		requestedPages = append(requestedPages, page)
+		fmt.Fprintf(w, `{"data": [{"id": %s, "name": "www%s", "type": "A", "content": "127.0.0.1", "ttl": 600}], "pagination": {"current_page": %s, "per_page": 1, "total_entries": 2, "total_pages": 2}}`, page, page, page)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	records, err := client.GetRecords("example.com")
+
+	// assert
+	if err != nil || len(records) != 2 || records[1].Name != "www2" {
+		t.Fail()
+		t.Logf("GetRecords() should return the records of both pages but returned %v, %v", records, err)
+	}
+
+	if fmt.Sprint(requestedPages) != "[1 2]" {
+		t.Fail()
+		t.Logf("GetRecords() should request the pages 1 and 2 but requested %v", requestedPages)
+	}
+}
+
+// The v2 page loops should stop with an error if the API does not advance to the next page.
+func Test_v2Client_PageIsRepeated_ErrorIsReturned(t *testing.T) {
+	// arrange
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"data": [], "pagination": {"current_page": 1, "per_page": 1, "total_entries": 3, "total_pages": 3}}`)
+	}))
+	defer server.Close()
+
+	client := newTestV2Client("1010", server)
+
+	// act
+	_, domainsError := client.GetDomainsContext(context.Background())
+	_, recordsError := client.GetFilteredRecordsContext(context.Background(), "example.com", RecordFilter{Name: "www"})
+
+	// assert
+	if domainsError == nil || recordsError == nil || requests != 4 {
+		t.Fail()
+		t.Logf("The v2 client should stop after the repeated page but returned %v, %v (requests: %d)", domainsError, recordsError, requests)
+	}
+}
+
+// Pages that link back to an earlier page should not be read forever.
+func Test_v1Client_TwoPageCycle_ErrorIsReturned(t *testing.T) {
+	// arrange
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		next := "2"
+		if r.URL.Query().Get("page") == "2" {
+			next = "1"
+		}
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%s>; rel="next"`, server.URL, r.URL.Path, next))
+		if r.URL.Path == "/domains" {
+			fmt.Fprint(w, `[{"domain": {"id": 1, "name": "example.com"}}]`)
+			return
+		}
+
+		fmt.Fprint(w, `[{"record": {"id": 1, "name": "www", "record_type": "A", "content": "127.0.0.1", "ttl": 600}}]`)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	_, recordsError := client.GetRecords("example.com")
+	_, domainsError := client.GetDomains()
+
+	iterator := NewRecordIterator(context.Background(), client, "example.com")
+	for iterator.Next() {
+	}
+
+	// assert
+	if recordsError == nil || domainsError == nil || iterator.Err() == nil {
+		t.Fail()
+		t.Logf("The page cycle should be reported but GetRecords(), GetDomains() and the iterator returned %v, %v, %v", recordsError, domainsError, iterator.Err())
+	}
+
+	if requests != 9 {
+		t.Fail()
+		t.Logf("Each page should be requested once per loop but %d requests were sent", requests)
+	}
+}
+
+// The iterator should request pages only when the records of the previous page have been read.
+func Test_RecordIterator_Pager_PagesAreRequestedLazily(t *testing.T) {
+	// arrange
+	requests := 0
+	server := testV1PagedServer(3, &requests)
+	defer server.Close()
+
+	iterator := NewRecordIterator(context.Background(), newTestV1Client(server), "example.com")
+
+	// act
+	iterator.Next()
+	requestsAfterFirstRecord := requests
+
+	names := []string{iterator.Record().Name}
+	for iterator.Next() {
+		names = append(names, iterator.Record().Name)
+	}
+
+	// assert
+	if requestsAfterFirstRecord != 1 {
+		t.Fail()
+		t.Logf("The iterator should request only the first page before the first record is read but sent %d requests", requestsAfterFirstRecord)
+	}
+
+	if iterator.Err() != nil || fmt.Sprint(names) != "[www1 www2 www3]" {
+		t.Fail()
+		t.Logf("The iterator should return the records of all pages but returned %v, %v", names, iterator.Err())
+	}
+}
+
+// The iterator should read all records at once from clients without pagination support.
+func Test_RecordIterator_NoPager_AllRecordsAreReturned(t *testing.T) {
+	// arrange
+	client := &testDNSClient{
+		getRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Name: "www"}, {Name: "mail"}}, nil
+		},
+	}
+
+	iterator := NewRecordIterator(context.Background(), client, "example.com")
+
+	// act
+	count := 0
+	for iterator.Next() {
+		count++
+	}
+
+	// assert
+	if iterator.Err() != nil || count != 2 {
+		t.Fail()
+		t.Logf("The iterator should return two records but returned %d, %v", count, iterator.Err())
+	}
+}
+
+// The iterator should stop with an error if a page cannot be read.
+func Test_RecordIterator_PageFails_ErrorIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s/domains/example.com/records?page=2>; rel="next"`, r.Host))
+		fmt.Fprint(w, `[{"record": {"id": 1, "name": "www", "record_type": "A", "content": "127.0.0.1", "ttl": 600}}]`)
+	}))
+	defer server.Close()
+
+	iterator := NewRecordIterator(context.Background(), newTestV1Client(server), "example.com")
+
+	// act
+	count := 0
+	for iterator.Next() {
+		count++
+	}
+
+	// assert
+	if count != 1 || iterator.Err() == nil {
+		t.Fail()
+		t.Logf("The iterator should return the first record and then an error but returned %d, %v", count, iterator.Err())
+	}
+}
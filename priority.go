@@ -0,0 +1,62 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"github.com/pearkes/dnsimple"
+)
+
+// ErrPriorityNotSupported is returned if a record with a priority (MX
+// or SRV) is written with a DNS client that does not implement the
+// PriorityRecordWriter interface.
+var ErrPriorityNotSupported = errors.New("The DNS client does not support record priorities")
+
+// PriorityRecordWriter is implemented by DNS clients that can set the
+// priority of records. dnsimple.ChangeRecord has no priority field, so
+// MX and SRV records can only be written with clients that implement
+// this interface.
+type PriorityRecordWriter interface {
+	// CreatePriorityRecordContext creates a new DNS record with the
+	// given priority for the given domain.
+	CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error)
+
+	// UpdatePriorityRecordContext updates the DNS record with the given
+	// id and sets its priority.
+	UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error)
+}
+
+// createPriorityRecord creates a DNS record with the given priority.
+func createPriorityRecord(ctx context.Context, client ContextDNSClient, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	writer, ok := priorityRecordWriter(client)
+	if !ok {
+		return "", ErrPriorityNotSupported
+	}
+
+	return writer.CreatePriorityRecordContext(ctx, domain, opts, priority)
+}
+
+// updatePriorityRecord updates the DNS record with the given id and sets its priority.
+func updatePriorityRecord(ctx context.Context, client ContextDNSClient, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	writer, ok := priorityRecordWriter(client)
+	if !ok {
+		return "", ErrPriorityNotSupported
+	}
+
+	return writer.UpdatePriorityRecordContext(ctx, domain, id, opts, priority)
+}
+
+// priorityRecordWriter returns the given client as a PriorityRecordWriter.
+// Clients wrapped in a context adapter are unwrapped first.
+func priorityRecordWriter(client ContextDNSClient) (PriorityRecordWriter, bool) {
+	if adapter, ok := client.(*contextDNSClientAdapter); ok {
+		writer, ok := adapter.client.(PriorityRecordWriter)
+		return writer, ok
+	}
+
+	writer, ok := client.(PriorityRecordWriter)
+	return writer, ok
+}
@@ -0,0 +1,357 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit describes the request quota reported by the DNSimple API.
+type RateLimit struct {
+	// Limit is the maximum number of requests per period.
+	Limit int
+
+	// Remaining is the number of requests left in the current period.
+	Remaining int
+
+	// Reset is the time at which the current period ends.
+	Reset time.Time
+}
+
+// RateLimitReporter is implemented by DNS clients that know the
+// request quota of the DNSimple API.
+type RateLimitReporter interface {
+	// RateLimit returns the most recently reported quota. The second
+	// return value is false if no quota has been reported yet.
+	RateLimit() (RateLimit, bool)
+}
+
+// parseRateLimit returns the quota that is reported by the
+// X-RateLimit-* headers of the given response.
+func parseRateLimit(header http.Header) (RateLimit, bool) {
+	limit, limitError := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, remainingError := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, resetError := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if limitError != nil || remainingError != nil || resetError != nil {
+		return RateLimit{}, false
+	}
+
+	return RateLimit{limit, remaining, time.Unix(reset, 0)}, true
+}
+
+// rateLimitOf returns the quota reported by the given client if it
+// implements the RateLimitReporter interface.
+func rateLimitOf(client interface{}) (RateLimit, bool) {
+	reporter, ok := client.(RateLimitReporter)
+	if !ok {
+		return RateLimit{}, false
+	}
+
+	return reporter.RateLimit()
+}
+
+// rateLimitRecorder stores the most recent quota reported by the API.
+type rateLimitRecorder struct {
+	lock      sync.Mutex
+	rateLimit RateLimit
+	known     bool
+}
+
+// record stores the quota reported by the given response headers.
+func (recorder *rateLimitRecorder) record(header http.Header) {
+	rateLimit, ok := parseRateLimit(header)
+	if !ok {
+		return
+	}
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	recorder.rateLimit = rateLimit
+	recorder.known = true
+}
+
+// RateLimit returns the most recently reported quota.
+func (recorder *rateLimitRecorder) RateLimit() (RateLimit, bool) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	return recorder.rateLimit, recorder.known
+}
+
+// RateLimitPolicy defines how the rate-limited client paces requests.
+type RateLimitPolicy struct {
+	// RequestsPerHour is the rate of the token bucket that is used
+	// while the API does not report a quota. It also paces the
+	// requests that waited for the reset of an exhausted quota.
+	RequestsPerHour int
+
+	// Burst is the number of requests that can be sent at once while
+	// the API does not report a quota.
+	Burst int
+
+	// Reserve is the number of requests of the reported quota that are
+	// left for other clients sharing the same account.
+	Reserve int
+}
+
+// DefaultRateLimitPolicy returns a policy that allows 2400 requests per
+// hour with bursts of up to 10 requests if the API does not report a
+// quota and keeps no reserve.
+func DefaultRateLimitPolicy() RateLimitPolicy {
+	return RateLimitPolicy{
+		RequestsPerHour: 2400,
+		Burst:           10,
+	}
+}
+
+// NewRateLimitedDNSClient creates a DNSClient that paces the requests
+// of the given client to stay within the quota of the DNSimple API.
+// If the given client reports the quota (see RateLimitReporter) the
+// remaining requests are spread evenly until the quota resets.
+// Otherwise requests are limited by a token bucket that is configured
+// with the given policy.
+//
+// Wrap the rate-limited client with NewRetryingDNSClient (and not the
+// other way round) so that retries are paced as well.
+//
+// The returned client also implements the ContextDNSClient, the
+// RecordPager, the RecordFilterer, the PriorityRecordWriter and the
+// RateLimitReporter interface.
+func NewRateLimitedDNSClient(client DNSClient, policy RateLimitPolicy) DNSClient {
+	if policy.RequestsPerHour < 1 {
+		policy.RequestsPerHour = DefaultRateLimitPolicy().RequestsPerHour
+	}
+
+	if policy.Burst < 1 {
+		policy.Burst = 1
+	}
+
+	return &rateLimitedDNSClient{
+		client:   AsContextDNSClient(client),
+		reporter: client,
+		policy:   policy,
+		tokens:   float64(policy.Burst),
+		now:      time.Now,
+		sleep:    sleepContext,
+	}
+}
+
+// rateLimitedDNSClient delays calls of the wrapped client to stay
+// within the API quota.
+type rateLimitedDNSClient struct {
+	client   ContextDNSClient
+	reporter interface{}
+	policy   RateLimitPolicy
+
+	lock        sync.Mutex
+	tokens      float64
+	lastRefill  time.Time
+	nextAllowed time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, duration time.Duration) error
+}
+
+// RateLimit returns the quota reported by the wrapped client.
+func (limitedClient *rateLimitedDNSClient) RateLimit() (RateLimit, bool) {
+	return rateLimitOf(limitedClient.reporter)
+}
+
+// UpdateRecord update the DNS record with the given id.
+func (limitedClient *rateLimitedDNSClient) UpdateRecord(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return limitedClient.UpdateRecordContext(context.Background(), domain, id, opts)
+}
+
+// GetRecords returns all DNS records for the given domain.
+func (limitedClient *rateLimitedDNSClient) GetRecords(domain string) ([]dnsimple.Record, error) {
+	return limitedClient.GetRecordsContext(context.Background(), domain)
+}
+
+// GetDomains returns a list of domain.
+func (limitedClient *rateLimitedDNSClient) GetDomains() ([]dnsimple.Domain, error) {
+	return limitedClient.GetDomainsContext(context.Background())
+}
+
+// CreateRecord creates a new DNS record for the given domain.
+func (limitedClient *rateLimitedDNSClient) CreateRecord(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return limitedClient.CreateRecordContext(context.Background(), domain, opts)
+}
+
+// DestroyRecord deletes the DNS record with the given id.
+func (limitedClient *rateLimitedDNSClient) DestroyRecord(domain string, id string) error {
+	return limitedClient.DestroyRecordContext(context.Background(), domain, id)
+}
+
+// UpdateRecordContext update the DNS record with the given id.
+func (limitedClient *rateLimitedDNSClient) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	if err := limitedClient.wait(ctx); err != nil {
+		return "", err
+	}
+
+	return limitedClient.client.UpdateRecordContext(ctx, domain, id, opts)
+}
+
+// GetRecordsContext returns all DNS records for the given domain.
+// Each page counts as a separate request.
+func (limitedClient *rateLimitedDNSClient) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return collectRecords(ctx, limitedClient, domain)
+}
+
+// GetRecordsPage returns the given page of DNS records for the given domain.
+func (limitedClient *rateLimitedDNSClient) GetRecordsPage(ctx context.Context, domain, page string) (RecordPage, error) {
+	if err := limitedClient.wait(ctx); err != nil {
+		return RecordPage{}, err
+	}
+
+	return getRecordsPage(ctx, limitedClient.client, domain, page)
+}
+
+// GetFilteredRecordsContext returns the DNS records of the given domain
+// that match the given filter.
+func (limitedClient *rateLimitedDNSClient) GetFilteredRecordsContext(ctx context.Context, domain string, filter RecordFilter) ([]dnsimple.Record, error) {
+	if _, ok := limitedClient.client.(RecordFilterer); !ok {
+		// pace every page of the unfiltered records
+		records, err := limitedClient.GetRecordsContext(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+
+		return filter.apply(records), nil
+	}
+
+	if err := limitedClient.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return getFilteredRecords(ctx, limitedClient.client, domain, filter)
+}
+
+// GetDomainsContext returns a list of domain.
+func (limitedClient *rateLimitedDNSClient) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	if err := limitedClient.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return limitedClient.client.GetDomainsContext(ctx)
+}
+
+// CreateRecordContext creates a new DNS record for the given domain.
+func (limitedClient *rateLimitedDNSClient) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	if err := limitedClient.wait(ctx); err != nil {
+		return "", err
+	}
+
+	return limitedClient.client.CreateRecordContext(ctx, domain, opts)
+}
+
+// CreatePriorityRecordContext creates a new DNS record with the given
+// priority for the given domain.
+func (limitedClient *rateLimitedDNSClient) CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	if err := limitedClient.wait(ctx); err != nil {
+		return "", err
+	}
+
+	return createPriorityRecord(ctx, limitedClient.client, domain, opts, priority)
+}
+
+// UpdatePriorityRecordContext updates the DNS record with the given id
+// and sets its priority.
+func (limitedClient *rateLimitedDNSClient) UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	if err := limitedClient.wait(ctx); err != nil {
+		return "", err
+	}
+
+	return updatePriorityRecord(ctx, limitedClient.client, domain, id, opts, priority)
+}
+
+// DestroyRecordContext deletes the DNS record with the given id.
+func (limitedClient *rateLimitedDNSClient) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	if err := limitedClient.wait(ctx); err != nil {
+		return err
+	}
+
+	return limitedClient.client.DestroyRecordContext(ctx, domain, id)
+}
+
+// wait blocks until the next request may be sent or the context is done.
+func (limitedClient *rateLimitedDNSClient) wait(ctx context.Context) error {
+	delay := limitedClient.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	return limitedClient.sleep(ctx, delay)
+}
+
+// reserve reserves a slot for the next request and returns how long
+// the caller has to wait for it.
+func (limitedClient *rateLimitedDNSClient) reserve() time.Duration {
+	limitedClient.lock.Lock()
+	defer limitedClient.lock.Unlock()
+
+	now := limitedClient.now()
+	if rateLimit, ok := limitedClient.RateLimit(); ok && rateLimit.Reset.After(now) {
+		return limitedClient.reserveFromQuota(now, rateLimit)
+	}
+
+	return limitedClient.reserveFromBucket(now)
+}
+
+// reserveFromQuota spreads the remaining requests of the reported quota
+// evenly until the quota resets. If the quota is exhausted the waiting
+// requests are sent one after another after the reset, paced by the
+// requests per hour of the policy.
+func (limitedClient *rateLimitedDNSClient) reserveFromQuota(now time.Time, rateLimit RateLimit) time.Duration {
+	untilReset := rateLimit.Reset.Sub(now)
+
+	available := rateLimit.Remaining - limitedClient.policy.Reserve
+	if available <= 0 {
+		start := rateLimit.Reset
+		if limitedClient.nextAllowed.After(start) {
+			start = limitedClient.nextAllowed
+		}
+
+		limitedClient.nextAllowed = start.Add(time.Hour / time.Duration(limitedClient.policy.RequestsPerHour))
+		return start.Sub(now)
+	}
+
+	start := now
+	if limitedClient.nextAllowed.After(now) {
+		start = limitedClient.nextAllowed
+	}
+
+	limitedClient.nextAllowed = start.Add(untilReset / time.Duration(available))
+	return start.Sub(now)
+}
+
+// reserveFromBucket takes a token from the token bucket and returns how
+// long the caller has to wait until the token is available.
+func (limitedClient *rateLimitedDNSClient) reserveFromBucket(now time.Time) time.Duration {
+	ratePerSecond := float64(limitedClient.policy.RequestsPerHour) / 3600
+
+	if !limitedClient.lastRefill.IsZero() {
+		elapsed := now.Sub(limitedClient.lastRefill).Seconds()
+		limitedClient.tokens += elapsed * ratePerSecond
+		if limitedClient.tokens > float64(limitedClient.policy.Burst) {
+			limitedClient.tokens = float64(limitedClient.policy.Burst)
+		}
+	}
+
+	limitedClient.lastRefill = now
+	limitedClient.tokens--
+
+	if limitedClient.tokens >= 0 {
+		return 0
+	}
+
+	// the token is taken in advance; the caller has to wait until it is refilled
+	return time.Duration(-limitedClient.tokens / ratePerSecond * float64(time.Second))
+}
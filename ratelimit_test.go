@@ -0,0 +1,151 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testRateLimitReporter is a DNS client that reports a fixed quota.
+type testRateLimitReporter struct {
+	testDNSClient
+
+	rateLimit RateLimit
+	known     bool
+}
+
+func (client *testRateLimitReporter) RateLimit() (RateLimit, bool) {
+	return client.rateLimit, client.known
+}
+
+// newTestRateLimitedDNSClient creates a rate-limited client with a fixed
+// clock that records the delays instead of sleeping.
+func newTestRateLimitedDNSClient(client DNSClient, policy RateLimitPolicy, now time.Time, delays *[]time.Duration) *rateLimitedDNSClient {
+	limitedClient := NewRateLimitedDNSClient(client, policy).(*rateLimitedDNSClient)
+	limitedClient.now = func() time.Time {
+		return now
+	}
+
+	limitedClient.sleep = func(ctx context.Context, duration time.Duration) error {
+		*delays = append(*delays, duration)
+		return nil
+	}
+
+	return limitedClient
+}
+
+// The v1 client should expose the quota reported by the API.
+func Test_v1Client_RateLimitHeaders_RateLimitIsReported(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "2400")
+		w.Header().Set("X-RateLimit-Remaining", "2399")
+		w.Header().Set("X-RateLimit-Reset", "1451649600")
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	client := newTestV1Client(server)
+
+	// act
+	_, beforeRequest := client.RateLimit()
+	client.GetDomains()
+	rateLimit, afterRequest := client.RateLimit()
+
+	// assert
+	if beforeRequest || !afterRequest || rateLimit.Limit != 2400 || rateLimit.Remaining != 2399 || rateLimit.Reset.Unix() != 1451649600 {
+		t.Fail()
+		t.Logf("RateLimit() should report the quota of the last response but returned %+v", rateLimit)
+	}
+}
+
+// The remaining requests should be spread evenly until the quota resets.
+func Test_RateLimitedDNSClient_QuotaIsReported_RequestsAreSpreadUntilReset(t *testing.T) {
+	// arrange
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	dnsClient := &testRateLimitReporter{
+		testDNSClient: testDNSClient{
+			getDomainsFunc: func() ([]dnsimple.Domain, error) {
+				return nil, nil
+			},
+		},
+		rateLimit: RateLimit{Limit: 100, Remaining: 10, Reset: now.Add(10 * time.Minute)},
+		known:     true,
+	}
+
+	var delays []time.Duration
+	client := newTestRateLimitedDNSClient(dnsClient, DefaultRateLimitPolicy(), now, &delays)
+
+	// act
+	client.GetDomains()
+	client.GetDomains()
+	client.GetDomains()
+
+	// assert
+	if len(delays) != 2 || delays[0] != time.Minute || delays[1] != 2*time.Minute {
+		t.Fail()
+		t.Logf("The requests should be one minute apart but the delays were %v", delays)
+	}
+}
+
+// If the quota is exhausted the client should wait for the reset and then send the waiting requests one after another.
+func Test_RateLimitedDNSClient_QuotaIsExhausted_ClientWaitsForReset(t *testing.T) {
+	// arrange
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	dnsClient := &testRateLimitReporter{
+		testDNSClient: testDNSClient{
+			getDomainsFunc: func() ([]dnsimple.Domain, error) {
+				return nil, nil
+			},
+		},
+		rateLimit: RateLimit{Limit: 100, Remaining: 5, Reset: now.Add(30 * time.Minute)},
+		known:     true,
+	}
+
+	var delays []time.Duration
+	client := newTestRateLimitedDNSClient(dnsClient, RateLimitPolicy{RequestsPerHour: 3600, Reserve: 5}, now, &delays)
+
+	// act
+	client.GetDomains()
+	client.GetDomains()
+	client.GetDomains()
+
+	// assert
+	if len(delays) != 3 || delays[0] != 30*time.Minute || delays[1] != 30*time.Minute+time.Second || delays[2] != 30*time.Minute+2*time.Second {
+		t.Fail()
+		t.Logf("The client should wait 30 minutes for the reset and then one second per request but the delays were %v", delays)
+	}
+}
+
+// Without a reported quota the token bucket should allow bursts and then limit the rate.
+func Test_RateLimitedDNSClient_NoQuotaReported_TokenBucketIsUsed(t *testing.T) {
+	// arrange
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	dnsClient := &testDNSClient{
+		getDomainsFunc: func() ([]dnsimple.Domain, error) {
+			return nil, nil
+		},
+	}
+
+	var delays []time.Duration
+	client := newTestRateLimitedDNSClient(dnsClient, RateLimitPolicy{RequestsPerHour: 3600, Burst: 2}, now, &delays)
+
+	// act
+	client.GetDomains()
+	client.GetDomains()
+	client.GetDomains()
+
+	// assert
+	if len(delays) != 1 || delays[0] != time.Second {
+		t.Fail()
+		t.Logf("The third request should wait one second but the delays were %v", delays)
+	}
+}
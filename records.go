@@ -0,0 +1,358 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hostnameLabelPattern defines a pattern for the labels of host names
+// that are used as record content. Underscores are allowed for service
+// names (e.g. "_sip._tcp.example.com").
+var hostnameLabelPattern = regexp.MustCompile(`^(?:[A-Za-z0-9_][A-Za-z0-9\-_]{0,61}[A-Za-z0-9]|[A-Za-z0-9_])$`)
+
+// caaTagPattern defines a pattern for the tags of CAA records (see RFC 8659).
+var caaTagPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,15}$`)
+
+// TypedRecord is a DNS record of a specific type whose content can be
+// validated before it is sent to the API.
+type TypedRecord interface {
+	// Type returns the record type (e.g. "MX").
+	Type() string
+
+	// Content returns the content of the record in the format of the
+	// DNSimple API.
+	Content() string
+
+	// Validate returns an error matching ErrInvalidArgument if the
+	// content of the record is invalid.
+	Validate() error
+}
+
+// PriorityRecord is implemented by typed records that have a priority
+// (MX and SRV). The priority is not part of the record content.
+type PriorityRecord interface {
+	TypedRecord
+
+	// Prio returns the priority of the record.
+	Prio() int
+}
+
+// CNAMERecord is an alias for another host name.
+type CNAMERecord struct {
+	// Target is the canonical host name.
+	Target string
+}
+
+// Type returns "CNAME".
+func (record CNAMERecord) Type() string {
+	return "CNAME"
+}
+
+// Content returns the target host name.
+func (record CNAMERecord) Content() string {
+	return normalizeHostname(record.Target)
+}
+
+// Validate checks the target host name.
+func (record CNAMERecord) Validate() error {
+	return validateHostname("CNAME target", record.Target)
+}
+
+// MXRecord is a mail exchanger.
+type MXRecord struct {
+	// Priority is the preference of the mail exchanger (lower values
+	// are preferred).
+	Priority int
+
+	// Host is the host name of the mail exchanger.
+	Host string
+}
+
+// Type returns "MX".
+func (record MXRecord) Type() string {
+	return "MX"
+}
+
+// Content returns the host name of the mail exchanger.
+func (record MXRecord) Content() string {
+	return normalizeHostname(record.Host)
+}
+
+// Prio returns the priority of the mail exchanger.
+func (record MXRecord) Prio() int {
+	return record.Priority
+}
+
+// Validate checks the priority and the host name.
+func (record MXRecord) Validate() error {
+	if err := validateUint16("MX priority", record.Priority); err != nil {
+		return err
+	}
+
+	return validateHostname("MX host", record.Host)
+}
+
+// TXTRecord is a text record (e.g. an SPF policy or a verification token).
+type TXTRecord struct {
+	// Text is the text of the record.
+	Text string
+}
+
+// Type returns "TXT".
+func (record TXTRecord) Type() string {
+	return "TXT"
+}
+
+// Content returns the text.
+func (record TXTRecord) Content() string {
+	return record.Text
+}
+
+// Validate checks that the text is not empty and contains no line breaks.
+func (record TXTRecord) Validate() error {
+	if record.Text == "" {
+		return newKindError(ErrInvalidArgument, "The TXT text is empty")
+	}
+
+	if strings.ContainsAny(record.Text, "\r\n") {
+		return newKindError(ErrInvalidArgument, "The TXT text must not contain line breaks: %q", record.Text)
+	}
+
+	return nil
+}
+
+// SRVRecord is the location of a service.
+type SRVRecord struct {
+	// Priority is the priority of the target (lower values are preferred).
+	Priority int
+
+	// Weight is the relative weight of targets with the same priority.
+	Weight int
+
+	// Port is the port of the service.
+	Port int
+
+	// Target is the host name of the service or "." if the service is
+	// not available.
+	Target string
+}
+
+// Type returns "SRV".
+func (record SRVRecord) Type() string {
+	return "SRV"
+}
+
+// Content returns the weight, the port and the target.
+func (record SRVRecord) Content() string {
+	target := normalizeHostname(record.Target)
+	if target == "" {
+		target = "."
+	}
+
+	return fmt.Sprintf("%d %d %s", record.Weight, record.Port, target)
+}
+
+// Prio returns the priority of the target.
+func (record SRVRecord) Prio() int {
+	return record.Priority
+}
+
+// Validate checks the priority, the weight, the port and the target.
+func (record SRVRecord) Validate() error {
+	if err := validateUint16("SRV priority", record.Priority); err != nil {
+		return err
+	}
+
+	if err := validateUint16("SRV weight", record.Weight); err != nil {
+		return err
+	}
+
+	if err := validateUint16("SRV port", record.Port); err != nil {
+		return err
+	}
+
+	if record.Target == "." {
+		return nil
+	}
+
+	return validateHostname("SRV target", record.Target)
+}
+
+// CAARecord restricts the certificate authorities that may issue
+// certificates for a domain.
+type CAARecord struct {
+	// Flags are the flags of the record (0 or 128 for critical).
+	Flags int
+
+	// Tag is the property (e.g. "issue", "issuewild" or "iodef").
+	Tag string
+
+	// Value is the value of the property (e.g. "letsencrypt.org").
+	Value string
+}
+
+// Type returns "CAA".
+func (record CAARecord) Type() string {
+	return "CAA"
+}
+
+// Content returns the flags, the tag and the quoted value.
+func (record CAARecord) Content() string {
+	return fmt.Sprintf(`%d %s "%s"`, record.Flags, record.Tag, record.Value)
+}
+
+// Validate checks the flags, the tag and the value.
+func (record CAARecord) Validate() error {
+	if record.Flags < 0 || record.Flags > 255 {
+		return newKindError(ErrInvalidArgument, "The CAA flags must be between 0 and 255: %d", record.Flags)
+	}
+
+	if !caaTagPattern.MatchString(record.Tag) {
+		return newKindError(ErrInvalidArgument, "The CAA tag is invalid: %q", record.Tag)
+	}
+
+	if strings.ContainsAny(record.Value, "\"\r\n") {
+		return newKindError(ErrInvalidArgument, "The CAA value must not contain quotes or line breaks: %q", record.Value)
+	}
+
+	return nil
+}
+
+// NSRecord delegates a domain to a name server.
+type NSRecord struct {
+	// Host is the host name of the name server.
+	Host string
+}
+
+// Type returns "NS".
+func (record NSRecord) Type() string {
+	return "NS"
+}
+
+// Content returns the host name of the name server.
+func (record NSRecord) Content() string {
+	return normalizeHostname(record.Host)
+}
+
+// Validate checks the host name of the name server.
+func (record NSRecord) Validate() error {
+	return validateHostname("NS host", record.Host)
+}
+
+// ParseRecord converts the given DNSimple record into a typed record.
+// It returns an error matching ErrInvalidArgument for unsupported
+// record types and malformed content.
+func ParseRecord(record dnsimple.Record) (TypedRecord, error) {
+	var typedRecord TypedRecord
+	switch record.RecordType {
+	case "CNAME":
+		typedRecord = CNAMERecord{Target: record.Content}
+
+	case "MX":
+		typedRecord = MXRecord{Priority: int(record.Prio), Host: record.Content}
+
+	case "TXT":
+		typedRecord = TXTRecord{Text: unquoteTXT(record.Content)}
+
+	case "SRV":
+		fields := strings.Fields(record.Content)
+		if len(fields) != 3 {
+			return nil, newKindError(ErrInvalidArgument, "Invalid SRV content: %q", record.Content)
+		}
+
+		weight, weightError := strconv.Atoi(fields[0])
+		port, portError := strconv.Atoi(fields[1])
+		if weightError != nil || portError != nil {
+			return nil, newKindError(ErrInvalidArgument, "Invalid SRV content: %q", record.Content)
+		}
+
+		typedRecord = SRVRecord{Priority: int(record.Prio), Weight: weight, Port: port, Target: fields[2]}
+
+	case "CAA":
+		fields := strings.SplitN(record.Content, " ", 3)
+		if len(fields) != 3 {
+			return nil, newKindError(ErrInvalidArgument, "Invalid CAA content: %q", record.Content)
+		}
+
+		flags, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, newKindError(ErrInvalidArgument, "Invalid CAA content: %q", record.Content)
+		}
+
+		typedRecord = CAARecord{Flags: flags, Tag: fields[1], Value: strings.Trim(fields[2], `"`)}
+
+	case "NS":
+		typedRecord = NSRecord{Host: record.Content}
+
+	default:
+		return nil, newKindError(ErrInvalidArgument, "Unsupported record type: %q", record.RecordType)
+	}
+
+	return typedRecord, nil
+}
+
+// recordMatches returns true if the given DNSimple record has the type,
+// the content and (for priority records) the priority of the given
+// typed record.
+func recordMatches(record dnsimple.Record, typedRecord TypedRecord) bool {
+	if record.RecordType != typedRecord.Type() {
+		return false
+	}
+
+	if priorityRecord, ok := typedRecord.(PriorityRecord); ok && record.Prio != int64(priorityRecord.Prio()) {
+		return false
+	}
+
+	if record.RecordType == "TXT" {
+		return unquoteTXT(record.Content) == typedRecord.Content()
+	}
+
+	return strings.EqualFold(strings.TrimSuffix(record.Content, "."), typedRecord.Content())
+}
+
+// unquoteTXT removes the quotes the API might add to TXT contents.
+func unquoteTXT(content string) string {
+	if len(content) >= 2 && strings.HasPrefix(content, `"`) && strings.HasSuffix(content, `"`) {
+		return content[1 : len(content)-1]
+	}
+
+	return content
+}
+
+// normalizeHostname returns the given host name without surrounding
+// white space and without a trailing dot.
+func normalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.TrimSpace(hostname), ".")
+}
+
+// validateHostname returns an error if the given host name is invalid.
+func validateHostname(description, hostname string) error {
+	name := normalizeHostname(hostname)
+	if name == "" || len(name) > 253 {
+		return newKindError(ErrInvalidArgument, "The %s is invalid: %q", description, hostname)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if !hostnameLabelPattern.MatchString(label) {
+			return newKindError(ErrInvalidArgument, "The %s is invalid: %q", description, hostname)
+		}
+	}
+
+	return nil
+}
+
+// validateUint16 returns an error if the given value is not between 0 and 65535.
+func validateUint16(description string, value int) error {
+	if value < 0 || value > 65535 {
+		return newKindError(ErrInvalidArgument, "The %s must be between 0 and 65535: %d", description, value)
+	}
+
+	return nil
+}
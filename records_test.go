@@ -0,0 +1,151 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"errors"
+	"github.com/pearkes/dnsimple"
+	"testing"
+)
+
+// Valid records should pass the validation and return the content in the format of the API.
+func Test_TypedRecord_ValidRecord_ContentIsFormatted(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		record  TypedRecord
+		content string
+	}{
+		{CNAMERecord{Target: "www.example.org."}, "www.example.org"},
+		{MXRecord{Priority: 10, Host: "mx.example.com"}, "mx.example.com"},
+		{TXTRecord{Text: "v=spf1 include:_spf.example.com -all"}, "v=spf1 include:_spf.example.com -all"},
+		{SRVRecord{Priority: 10, Weight: 60, Port: 5060, Target: "sip.example.com"}, "60 5060 sip.example.com"},
+		{SRVRecord{Target: "."}, "0 0 ."},
+		{CAARecord{Flags: 0, Tag: "issue", Value: "letsencrypt.org"}, `0 issue "letsencrypt.org"`},
+		{NSRecord{Host: "ns1.dnsimple.com"}, "ns1.dnsimple.com"},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		err := input.record.Validate()
+		content := input.record.Content()
+
+		// assert
+		if err != nil || content != input.content {
+			t.Fail()
+			t.Logf("%#v should be valid with the content %q but returned %q, %v", input.record, input.content, content, err)
+		}
+	}
+}
+
+// Invalid records should be rejected with ErrInvalidArgument.
+func Test_TypedRecord_InvalidRecord_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []TypedRecord{
+		CNAMERecord{},
+		CNAMERecord{Target: "www example.org"},
+		MXRecord{Priority: -1, Host: "mx.example.com"},
+		MXRecord{Priority: 65536, Host: "mx.example.com"},
+		MXRecord{Priority: 10, Host: ""},
+		TXTRecord{},
+		TXTRecord{Text: "line\nbreak"},
+		SRVRecord{Port: 70000, Target: "sip.example.com"},
+		SRVRecord{Target: "-sip.example.com"},
+		CAARecord{Flags: 256, Tag: "issue", Value: "letsencrypt.org"},
+		CAARecord{Tag: "", Value: "letsencrypt.org"},
+		CAARecord{Tag: "issue", Value: `lets"encrypt.org`},
+		NSRecord{Host: "ns1..dnsimple.com"},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		err := input.Validate()
+
+		// assert
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Fail()
+			t.Logf("Validate() should reject %#v but returned %v", input, err)
+		}
+	}
+}
+
+// ParseRecord should convert the records of the API into typed records.
+func Test_ParseRecord_SupportedTypes_TypedRecordsAreReturned(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		record   dnsimple.Record
+		expected TypedRecord
+	}{
+		{dnsimple.Record{RecordType: "CNAME", Content: "www.example.org"}, CNAMERecord{Target: "www.example.org"}},
+		{dnsimple.Record{RecordType: "MX", Content: "mx.example.com", Prio: 10}, MXRecord{Priority: 10, Host: "mx.example.com"}},
+		{dnsimple.Record{RecordType: "TXT", Content: `"google-site-verification=abc"`}, TXTRecord{Text: "google-site-verification=abc"}},
+		{dnsimple.Record{RecordType: "SRV", Content: "60 5060 sip.example.com", Prio: 10}, SRVRecord{Priority: 10, Weight: 60, Port: 5060, Target: "sip.example.com"}},
+		{dnsimple.Record{RecordType: "CAA", Content: `128 iodef "mailto:security@example.com"`}, CAARecord{Flags: 128, Tag: "iodef", Value: "mailto:security@example.com"}},
+		{dnsimple.Record{RecordType: "NS", Content: "ns1.dnsimple.com"}, NSRecord{Host: "ns1.dnsimple.com"}},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		result, err := ParseRecord(input.record)
+
+		// assert
+		if err != nil || result != input.expected {
+			t.Fail()
+			t.Logf("ParseRecord(%+v) should return %#v but returned %#v, %v", input.record, input.expected, result, err)
+		}
+	}
+}
+
+// ParseRecord should reject unsupported types and malformed contents.
+func Test_ParseRecord_UnsupportedOrMalformed_ErrorIsReturned(t *testing.T) {
+	// arrange
+	inputs := []dnsimple.Record{
+		{RecordType: "A", Content: "127.0.0.1"},
+		{RecordType: "SRV", Content: "60 sip.example.com"},
+		{RecordType: "CAA", Content: "issue"},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		_, err := ParseRecord(input)
+
+		// assert
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Fail()
+			t.Logf("ParseRecord(%+v) should return ErrInvalidArgument but returned %v", input, err)
+		}
+	}
+}
+
+// recordMatches should compare the type, the content and the priority.
+func Test_recordMatches(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		record      dnsimple.Record
+		typedRecord TypedRecord
+		expected    bool
+	}{
+		{dnsimple.Record{RecordType: "MX", Content: "mx.example.com", Prio: 10}, MXRecord{10, "MX.example.com."}, true},
+		{dnsimple.Record{RecordType: "MX", Content: "mx.example.com", Prio: 20}, MXRecord{10, "mx.example.com"}, false},
+		{dnsimple.Record{RecordType: "TXT", Content: `"token"`}, TXTRecord{"token"}, true},
+		{dnsimple.Record{RecordType: "TXT", Content: "Token"}, TXTRecord{"token"}, false},
+		{dnsimple.Record{RecordType: "NS", Content: "mx.example.com"}, MXRecord{0, "mx.example.com"}, false},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		result := recordMatches(input.record, input.typedRecord)
+
+		// assert
+		if result != input.expected {
+			t.Fail()
+			t.Logf("recordMatches(%+v, %#v) should return %t", input.record, input.typedRecord, input.expected)
+		}
+	}
+}
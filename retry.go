@@ -0,0 +1,388 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how often and how long a failed request is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per call
+	// (including the first one).
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. The delay
+	// is doubled for every further retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the upper limit of the delay between two attempts.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy returns a policy that makes up to four attempts
+// with delays between 0.5 and 30 seconds.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// NewRetryingDNSClient creates a DNSClient that retries calls of the
+// given client that failed because of network errors, server errors
+// (5xx) or rate limiting (429). Delays requested by the API with the
+// Retry-After header are honored; otherwise the delay grows
+// exponentially with random jitter.
+//
+// CreateRecord is not idempotent. The client looks up the matching
+// records before it sends a create request. If the request might have
+// reached the API before it failed, the client checks whether a new
+// matching record exists before it sends the request again.
+//
+// The returned client also implements the ContextDNSClient, the
+// RecordPager, the RecordFilterer and the PriorityRecordWriter interface.
+func NewRetryingDNSClient(client DNSClient, policy RetryPolicy) DNSClient {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	return &retryingDNSClient{
+		client: AsContextDNSClient(client),
+		policy: policy,
+		sleep:  sleepContext,
+		random: rand.Float64,
+	}
+}
+
+// retryingDNSClient retries failed calls of the wrapped client.
+type retryingDNSClient struct {
+	client ContextDNSClient
+	policy RetryPolicy
+
+	sleep  func(ctx context.Context, duration time.Duration) error
+	random func() float64
+}
+
+// RateLimit returns the quota reported by the wrapped client.
+func (retryingClient *retryingDNSClient) RateLimit() (RateLimit, bool) {
+	return rateLimitOf(retryingClient.client)
+}
+
+// UpdateRecord update the DNS record with the given id.
+func (retryingClient *retryingDNSClient) UpdateRecord(domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return retryingClient.UpdateRecordContext(context.Background(), domain, id, opts)
+}
+
+// GetRecords returns all DNS records for the given domain.
+func (retryingClient *retryingDNSClient) GetRecords(domain string) ([]dnsimple.Record, error) {
+	return retryingClient.GetRecordsContext(context.Background(), domain)
+}
+
+// GetDomains returns a list of domain.
+func (retryingClient *retryingDNSClient) GetDomains() ([]dnsimple.Domain, error) {
+	return retryingClient.GetDomainsContext(context.Background())
+}
+
+// CreateRecord creates a new DNS record for the given domain.
+func (retryingClient *retryingDNSClient) CreateRecord(domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return retryingClient.CreateRecordContext(context.Background(), domain, opts)
+}
+
+// DestroyRecord deletes the DNS record with the given id.
+func (retryingClient *retryingDNSClient) DestroyRecord(domain string, id string) error {
+	return retryingClient.DestroyRecordContext(context.Background(), domain, id)
+}
+
+// UpdateRecordContext update the DNS record with the given id.
+// Updates are idempotent and are retried on every temporary error.
+func (retryingClient *retryingDNSClient) UpdateRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	var result string
+	err := retryingClient.retry(ctx, func() (bool, error) {
+		var err error
+		result, err = retryingClient.client.UpdateRecordContext(ctx, domain, id, opts)
+		return isRetryableError(err), err
+	})
+
+	return result, err
+}
+
+// GetRecordsContext returns all DNS records for the given domain.
+// Each page is retried separately.
+func (retryingClient *retryingDNSClient) GetRecordsContext(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return collectRecords(ctx, retryingClient, domain)
+}
+
+// GetRecordsPage returns the given page of DNS records for the given domain.
+func (retryingClient *retryingDNSClient) GetRecordsPage(ctx context.Context, domain, page string) (RecordPage, error) {
+	var result RecordPage
+	err := retryingClient.retry(ctx, func() (bool, error) {
+		var err error
+		result, err = getRecordsPage(ctx, retryingClient.client, domain, page)
+		return isRetryableError(err), err
+	})
+
+	return result, err
+}
+
+// GetFilteredRecordsContext returns the DNS records of the given domain
+// that match the given filter.
+func (retryingClient *retryingDNSClient) GetFilteredRecordsContext(ctx context.Context, domain string, filter RecordFilter) ([]dnsimple.Record, error) {
+	var records []dnsimple.Record
+	err := retryingClient.retry(ctx, func() (bool, error) {
+		var err error
+		records, err = getFilteredRecords(ctx, retryingClient.client, domain, filter)
+		return isRetryableError(err), err
+	})
+
+	return records, err
+}
+
+// GetDomainsContext returns a list of domain.
+func (retryingClient *retryingDNSClient) GetDomainsContext(ctx context.Context) ([]dnsimple.Domain, error) {
+	var domains []dnsimple.Domain
+	err := retryingClient.retry(ctx, func() (bool, error) {
+		var err error
+		domains, err = retryingClient.client.GetDomainsContext(ctx)
+		return isRetryableError(err), err
+	})
+
+	return domains, err
+}
+
+// CreateRecordContext creates a new DNS record for the given domain.
+// Requests that were rejected before they were processed (429 or
+// connection failures) are retried right away. For all other temporary
+// errors the client first checks whether the record was created anyway
+// and returns the ID of the existing record instead of creating a
+// duplicate. If that check fails the original error is returned.
+func (retryingClient *retryingDNSClient) CreateRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return retryingClient.createRecord(ctx, domain, opts, func() (string, error) {
+		return retryingClient.client.CreateRecordContext(ctx, domain, opts)
+	})
+}
+
+// CreatePriorityRecordContext creates a new DNS record with the given
+// priority for the given domain. It is retried like CreateRecordContext.
+func (retryingClient *retryingDNSClient) CreatePriorityRecordContext(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	return retryingClient.createRecord(ctx, domain, opts, func() (string, error) {
+		return createPriorityRecord(ctx, retryingClient.client, domain, opts, priority)
+	})
+}
+
+// UpdatePriorityRecordContext updates the DNS record with the given id
+// and sets its priority. Updates are idempotent and are retried on every
+// temporary error.
+func (retryingClient *retryingDNSClient) UpdatePriorityRecordContext(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord, priority int) (string, error) {
+	var result string
+	err := retryingClient.retry(ctx, func() (bool, error) {
+		var err error
+		result, err = updatePriorityRecord(ctx, retryingClient.client, domain, id, opts, priority)
+		return isRetryableError(err), err
+	})
+
+	return result, err
+}
+
+// createRecord calls the given create function until the record was
+// created without creating duplicates. The matching records that exist
+// before the first attempt are remembered, so that only a record that
+// was created by an earlier attempt is taken over.
+func (retryingClient *retryingDNSClient) createRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord, create func() (string, error)) (string, error) {
+	var existingIDs map[string]bool
+	lookupError := retryingClient.retry(ctx, func() (bool, error) {
+		var err error
+		existingIDs, err = retryingClient.findRecordIDs(ctx, domain, opts)
+		return isRetryableError(err), err
+	})
+
+	if lookupError != nil {
+		return "", lookupError
+	}
+
+	var id string
+	var createError error
+	mightHaveBeenCreated := false
+
+	err := retryingClient.retry(ctx, func() (bool, error) {
+		if mightHaveBeenCreated {
+			ids, lookupError := retryingClient.findRecordIDs(ctx, domain, opts)
+			if lookupError != nil {
+				return false, createError
+			}
+
+			for createdID := range ids {
+				if !existingIDs[createdID] {
+					id = createdID
+					return false, nil
+				}
+			}
+		}
+
+		id, createError = create()
+		if !isRetryableError(createError) {
+			return false, createError
+		}
+
+		if !isRejectedBeforeProcessing(createError) {
+			mightHaveBeenCreated = true
+		}
+
+		return true, createError
+	})
+
+	return id, err
+}
+
+// DestroyRecordContext deletes the DNS record with the given id.
+// If an earlier attempt might have deleted the record, a "not found"
+// response to a retry is treated as success.
+func (retryingClient *retryingDNSClient) DestroyRecordContext(ctx context.Context, domain string, id string) error {
+	mightHaveBeenDeleted := false
+
+	return retryingClient.retry(ctx, func() (bool, error) {
+		err := retryingClient.client.DestroyRecordContext(ctx, domain, id)
+		if mightHaveBeenDeleted && hasStatusCode(err, http.StatusNotFound) {
+			return false, nil
+		}
+
+		if isRetryableError(err) && !isRejectedBeforeProcessing(err) {
+			mightHaveBeenDeleted = true
+		}
+
+		return isRetryableError(err), err
+	})
+}
+
+// findRecordIDs returns the IDs of the records of the given domain
+// that match the name, type and content of the given change record.
+func (retryingClient *retryingDNSClient) findRecordIDs(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (map[string]bool, error) {
+	records, err := retryingClient.client.GetRecordsContext(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool)
+	for _, record := range records {
+		if record.Name == opts.Name && record.RecordType == opts.Type && record.Content == opts.Value {
+			ids[record.StringId()] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// retry calls the given operation until it succeeds, reports that the
+// error is permanent, the maximum number of attempts is reached or the
+// context is done. If the context is done while waiting for the next
+// attempt the returned error matches the error of the context.
+func (retryingClient *retryingDNSClient) retry(ctx context.Context, operation func() (bool, error)) error {
+	var err error
+	for attempt := 0; attempt < retryingClient.policy.MaxAttempts; attempt++ {
+		var retryable bool
+		retryable, err = operation()
+		if err == nil || !retryable {
+			return err
+		}
+
+		// no delay after the last attempt
+		if attempt == retryingClient.policy.MaxAttempts-1 {
+			break
+		}
+
+		if sleepError := retryingClient.sleep(ctx, retryingClient.backoff(attempt, err)); sleepError != nil {
+			return fmt.Errorf("%w (last error: %s)", sleepError, err.Error())
+		}
+	}
+
+	return err
+}
+
+// backoff returns the delay before the next attempt. The Retry-After
+// delay of an APIError takes precedence over the exponential backoff,
+// but it is limited to the maximum backoff as well.
+func (retryingClient *retryingDNSClient) backoff(attempt int, err error) time.Duration {
+	var apiError *APIError
+	if errors.As(err, &apiError) && apiError.RetryAfter > 0 {
+		if retryingClient.policy.MaxBackoff > 0 && apiError.RetryAfter > retryingClient.policy.MaxBackoff {
+			return retryingClient.policy.MaxBackoff
+		}
+
+		return apiError.RetryAfter
+	}
+
+	delay := retryingClient.policy.InitialBackoff << uint(attempt)
+	if delay <= 0 || (retryingClient.policy.MaxBackoff > 0 && delay > retryingClient.policy.MaxBackoff) {
+		delay = retryingClient.policy.MaxBackoff
+	}
+
+	// use a random delay between 50% and 100% of the backoff
+	half := delay / 2
+	return half + time.Duration(retryingClient.random()*float64(half))
+}
+
+// isRetryableError returns true if the given error is temporary: a
+// temporary API error, a network timeout, a reset or refused connection
+// or a response that ended unexpectedly. Other transport errors (e.g.
+// invalid URLs or TLS certificate errors) are permanent.
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.Temporary()
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netError net.Error
+	return errors.As(err, &netError) && (netError.Timeout() || netError.Temporary())
+}
+
+// isRejectedBeforeProcessing returns true if the given error proves that
+// the API did not process the request: either the API rejected it
+// because of rate limiting or the connection could not be established.
+func isRejectedBeforeProcessing(err error) bool {
+	if hasStatusCode(err, http.StatusTooManyRequests) {
+		return true
+	}
+
+	var operationError *net.OpError
+	return errors.As(err, &operationError) && operationError.Op == "dial"
+}
+
+// hasStatusCode returns true if the given error is an APIError with
+// the given status code.
+func hasStatusCode(err error, statusCode int) bool {
+	var apiError *APIError
+	return errors.As(err, &apiError) && apiError.StatusCode == statusCode
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
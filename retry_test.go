@@ -0,0 +1,385 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestRetryingDNSClient creates a retrying client for the given test
+// server that does not sleep but records the requested delays.
+func newTestRetryingDNSClient(server *httptest.Server, delays *[]time.Duration) *retryingDNSClient {
+	client := NewRetryingDNSClient(newTestV1Client(server), RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+	}).(*retryingDNSClient)
+
+	client.sleep = func(ctx context.Context, duration time.Duration) error {
+		*delays = append(*delays, duration)
+		return nil
+	}
+
+	return client
+}
+
+// testAPIServer returns a test server that responds with the given
+// handlers in the given order and counts the requests.
+func testAPIServer(handlers ...http.HandlerFunc) (*httptest.Server, *int) {
+	var lock sync.Mutex
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		handler := handlers[len(handlers)-1]
+		if requests < len(handlers) {
+			handler = handlers[requests]
+		}
+		requests++
+		lock.Unlock()
+
+		handler(w, r)
+	}))
+
+	return server, &requests
+}
+
+// respond returns a handler that responds with the given status and body.
+func respond(statusCode int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+		fmt.Fprint(w, body)
+	}
+}
+
+// Server errors should be retried until the request succeeds.
+func Test_RetryingDNSClient_ServerErrorThenSuccess_RecordsAreReturned(t *testing.T) {
+	// arrange
+	server, requests := testAPIServer(
+		respond(http.StatusBadGateway, ""),
+		respond(http.StatusServiceUnavailable, ""),
+		respond(http.StatusOK, `[{"record": {"id": 1, "name": "www"}}]`),
+	)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	records, err := client.GetRecords("example.com")
+
+	// assert
+	if err != nil || len(records) != 1 || *requests != 3 {
+		t.Fail()
+		t.Logf("GetRecords() should succeed after two retries but returned %v, %v after %d requests", records, err, *requests)
+	}
+
+	if len(delays) != 2 || delays[0] < 500*time.Millisecond || delays[0] > time.Second || delays[1] < time.Second || delays[1] > 2*time.Second {
+		t.Fail()
+		t.Logf("The delays should grow exponentially with jitter but were %v", delays)
+	}
+}
+
+// The delay requested with Retry-After should be used for the next attempt.
+func Test_RetryingDNSClient_TooManyRequestsWithRetryAfter_RetryAfterIsHonored(t *testing.T) {
+	// arrange
+	server, _ := testAPIServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		respond(http.StatusOK, `[]`),
+	)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	_, err := client.GetDomains()
+
+	// assert
+	if err != nil || len(delays) != 1 || delays[0] != 7*time.Second {
+		t.Fail()
+		t.Logf("GetDomains() should have waited 7 seconds before the retry but returned %v after %v", err, delays)
+	}
+}
+
+// Retry-After delays above the maximum backoff should be limited.
+func Test_RetryingDNSClient_RetryAfterAboveMaxBackoff_MaxBackoffIsUsed(t *testing.T) {
+	// arrange
+	server, _ := testAPIServer(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		respond(http.StatusOK, `[]`),
+	)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	_, err := client.GetDomains()
+
+	// assert
+	if err != nil || len(delays) != 1 || delays[0] != time.Minute {
+		t.Fail()
+		t.Logf("GetDomains() should have waited one minute before the retry but returned %v after %v", err, delays)
+	}
+}
+
+// Cancelling the context while waiting for a retry should return the error of the context.
+func Test_RetryingDNSClient_ContextCancelledDuringBackoff_CanceledErrorIsReturned(t *testing.T) {
+	// arrange
+	server, requests := testAPIServer(respond(http.StatusInternalServerError, ""))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.sleep = func(ctx context.Context, duration time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	// act
+	_, err := client.GetDomainsContext(ctx)
+
+	// assert
+	if !errors.Is(err, context.Canceled) || *requests != 1 {
+		t.Fail()
+		t.Logf("GetDomainsContext() should return context.Canceled after one request but returned %v after %d requests", err, *requests)
+	}
+}
+
+// Client errors must not be retried.
+func Test_RetryingDNSClient_ClientError_RequestIsNotRetried(t *testing.T) {
+	// arrange
+	server, requests := testAPIServer(respond(http.StatusUnauthorized, ""))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	_, err := client.GetDomains()
+
+	// assert
+	if err == nil || *requests != 1 {
+		t.Fail()
+		t.Logf("GetDomains() should fail after the first request but returned %v after %d requests", err, *requests)
+	}
+}
+
+// The client should give up after the maximum number of attempts.
+func Test_RetryingDNSClient_PermanentServerError_ErrorIsReturnedAfterMaxAttempts(t *testing.T) {
+	// arrange
+	server, requests := testAPIServer(respond(http.StatusInternalServerError, ""))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	_, err := client.UpdateRecord("example.com", "1", &dnsimple.ChangeRecord{Value: "::1"})
+
+	// assert
+	if err == nil || *requests != 3 {
+		t.Fail()
+		t.Logf("UpdateRecord() should fail after 3 attempts but returned %v after %d requests", err, *requests)
+	}
+}
+
+// If a create request failed with a server error, but the record was
+// created anyway, the existing record must be returned instead of
+// creating a duplicate.
+func Test_RetryingDNSClient_CreateRecordFailsButRecordWasCreated_NoDuplicateIsCreated(t *testing.T) {
+	// arrange
+	creates := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			creates++
+			w.WriteHeader(http.StatusGatewayTimeout)
+		case "GET":
+			if creates == 0 {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+
+			fmt.Fprint(w, `[{"record": {"id": 42, "name": "www", "record_type": "A", "content": "127.0.0.1"}}]`)
+		}
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	id, err := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"})
+
+	// assert
+	if err != nil || id != "42" || creates != 1 {
+		t.Fail()
+		t.Logf("CreateRecord() should return the existing record 42 after one create request but returned %q, %v after %d creates", id, err, creates)
+	}
+}
+
+// Records that existed before the first create request must not be
+// taken over after a failed create request.
+func Test_RetryingDNSClient_CreateRecordFailsAndRecordExistedBefore_CreateIsRetried(t *testing.T) {
+	// arrange
+	creates := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			creates++
+			if creates == 1 {
+				w.WriteHeader(http.StatusGatewayTimeout)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"record": {"id": 43}}`)
+		case "GET":
+			fmt.Fprint(w, `[{"record": {"id": 42, "name": "www", "record_type": "A", "content": "127.0.0.1"}}]`)
+		}
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	id, err := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"})
+
+	// assert
+	if err != nil || id != "43" || creates != 2 {
+		t.Fail()
+		t.Logf("CreateRecord() should create the record 43 on the second attempt but returned %q, %v after %d creates", id, err, creates)
+	}
+}
+
+// If a create request failed with a server error and the record does
+// not exist, the create request should be sent again.
+func Test_RetryingDNSClient_CreateRecordFailsAndRecordDoesNotExist_CreateIsRetried(t *testing.T) {
+	// arrange
+	creates := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			creates++
+			if creates == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"record": {"id": 43}}`)
+		case "GET":
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newTestRetryingDNSClient(server, &delays)
+
+	// act
+	id, err := client.CreateRecord("example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "127.0.0.1"})
+
+	// assert
+	if err != nil || id != "43" || creates != 2 {
+		t.Fail()
+		t.Logf("CreateRecord() should create the record on the second attempt but returned %q, %v after %d creates", id, err, creates)
+	}
+}
+
+// Network errors should be retried.
+func Test_isRetryableError_NetworkErrors_ResultIsTrue(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	client := newTestV1Client(server)
+	server.Close()
+
+	// act
+	_, err := client.GetDomains()
+
+	// assert
+	if !isRetryableError(err) || !isRejectedBeforeProcessing(err) {
+		t.Fail()
+		t.Logf("A refused connection should be retryable and rejected before processing: %v", err)
+	}
+}
+
+// Transport errors that are not temporary must not be retried.
+func Test_isRetryableError_TransportErrors(t *testing.T) {
+	inputs := []struct {
+		err       error
+		retryable bool
+	}{
+		{&url.Error{Op: "Get", URL: "https://api.dnsimple.com", Err: io.ErrUnexpectedEOF}, true},
+		{&url.Error{Op: "Get", URL: "https://api.dnsimple.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}, true},
+		{&url.Error{Op: "Get", URL: "https://api.dnsimple.com", Err: &net.DNSError{Err: "timeout", IsTimeout: true}}, true},
+		{&url.Error{Op: "Get", URL: "https://api.dnsimple.com", Err: x509.UnknownAuthorityError{}}, false},
+		{&url.Error{Op: "Get", URL: "ftp://api.dnsimple.com", Err: errors.New("unsupported protocol scheme")}, false},
+		{&url.Error{Op: "Get", URL: "https://api.dnsimple.com", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		result := isRetryableError(input.err)
+
+		// assert
+		if result != input.retryable {
+			t.Fail()
+			t.Logf("isRetryableError(%v) should return %t but returned %t", input.err, input.retryable, result)
+		}
+	}
+}
+
+// Retry-After values can be seconds or HTTP dates.
+func Test_parseRetryAfter_SecondsOrDate_DelayIsReturned(t *testing.T) {
+	// arrange
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	inputs := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-1", 0},
+		{"Fri, 01 Jan 2016 12:01:00 GMT", time.Minute},
+		{"garbage", 0},
+	}
+
+	for _, input := range inputs {
+		// act
+		result := parseRetryAfter(input.value, now)
+
+		// assert
+		if result != input.expected {
+			t.Fail()
+			t.Logf("parseRetryAfter(%q) should return %s but returned %s", input.value, input.expected, result)
+		}
+	}
+}
@@ -34,6 +34,12 @@ func isValidDomain(domain string) bool {
 	return isEmpty(domain) == false
 }
 
+// normalizeDomain returns the given domain name in lower case and
+// without a trailing dot.
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
 // isValidSubdomain returns true if the given subdomain name is valid; otherwise false.
 // Note: This is not a real validation. I just want to exclude total garbage.
 func isValidSubdomain(subdomain string) bool {
@@ -57,6 +63,37 @@ func isValidSubdomain(subdomain string) bool {
 	return true
 }
 
+// recordNameLabelPattern defines a pattern for the labels of record
+// names. Other than subdomain labels they can start with an underscore
+// (e.g. "_acme-challenge" or "_sip._tcp").
+var recordNameLabelPattern = regexp.MustCompile(`^(?:_?[A-Za-z0-9][A-Za-z0-9\-_]{0,61}[A-Za-z0-9]|_?[A-Za-z0-9])$`)
+
+// isValidRecordName returns true if the given record name is valid; otherwise false.
+// The empty name (the apex of the domain), service names with underscores
+// and wildcards are valid.
+func isValidRecordName(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	if len(name) > 253 {
+		// too long
+		return false
+	}
+
+	for index, label := range strings.Split(name, ".") {
+		if index == 0 && label == "*" {
+			continue
+		}
+
+		if len(label) > 63 || !recordNameLabelPattern.MatchString(label) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // getDNSRecordTypeByIP returns the DNS record type for the given IP.
 // It will return "A" for an IPv4 address and "AAAA" for an IPv6 address.
 func getDNSRecordTypeByIP(ip net.IP) string {
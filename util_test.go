@@ -105,6 +105,56 @@ func Test_isValidSubdomain_GivenTextIsInvalid_ResultIsFalse(t *testing.T) {
 	}
 }
 
+func Test_isValidRecordName_GivenTextIsValid_ResultIsTrue(t *testing.T) {
+
+	// arrange
+	inputs := []string{
+		"",
+		"www",
+		"_acme-challenge",
+		"_acme-challenge.www",
+		"_sip._tcp",
+		"*",
+		"*.dev",
+	}
+
+	for _, input := range inputs {
+		// act
+		result := isValidRecordName(input)
+
+		// assert
+		if result == false {
+			t.Fail()
+			t.Logf("isValidRecordName(%q) should have returned true", input)
+		}
+	}
+}
+
+func Test_isValidRecordName_GivenTextIsInvalid_ResultIsFalse(t *testing.T) {
+
+	// arrange
+	inputs := []string{
+		" ",
+		"www ",
+		"-a",
+		"__a",
+		"a.*",
+		"www.",
+		"abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijkl",
+	}
+
+	for _, input := range inputs {
+		// act
+		result := isValidRecordName(input)
+
+		// assert
+		if result == true {
+			t.Fail()
+			t.Logf("isValidRecordName(%q) should have returned false", input)
+		}
+	}
+}
+
 // If the given IP is an IPv4 address, "A" should be returned as the record type.
 func Test_getDNSRecordTypeByIP_IPisIPv4_AIsReturned(t *testing.T) {
 	// arrange
@@ -120,3 +170,25 @@ func Test_getDNSRecordTypeByIP_IPisIPv4_AIsReturned(t *testing.T) {
 	}
 
 }
+
+// normalizeDomain should ignore case and a trailing dot.
+func Test_normalizeDomain_DomainWithUpperCaseAndTrailingDot_NormalizedDomainIsReturned(t *testing.T) {
+	// arrange
+	inputs := []string{
+		"example.com",
+		"Example.COM",
+		"example.com.",
+		" example.com ",
+	}
+
+	for _, input := range inputs {
+		// act
+		result := normalizeDomain(input)
+
+		// assert
+		if result != "example.com" {
+			t.Fail()
+			t.Logf("normalizeDomain(%q) should return %q but returned %q", input, "example.com", result)
+		}
+	}
+}
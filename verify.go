@@ -0,0 +1,197 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+)
+
+// ErrInvalidCredentials is returned by CredentialVerification.Err if
+// the DNSimple API rejected the credentials.
+var ErrInvalidCredentials = errors.New("The credentials were rejected by the DNSimple API")
+
+// CredentialVerification contains the result of VerifyCredentials.
+type CredentialVerification struct {
+	// Valid is true if the DNSimple API accepted the credentials.
+	Valid bool
+
+	// Kind is the type of the verified credentials.
+	Kind CredentialKind
+
+	// Email is the e-mail address the credentials belong to as
+	// reported by the API. It is empty for domain tokens.
+	Email string
+
+	// AccountID is the ID of the DNSimple account the credentials
+	// belong to as reported by the API (the user for API v1, the owner
+	// of the domain for domain tokens). It is zero for API v2 user tokens.
+	AccountID int
+
+	// Domains contains the names of all domains that can be
+	// accessed with the credentials.
+	Domains []string
+
+	// Message contains the reason given by the API if the
+	// credentials were rejected.
+	Message string
+}
+
+// Err returns ErrInvalidCredentials if the credentials are not valid.
+func (verification CredentialVerification) Err() error {
+	if verification.Valid {
+		return nil
+	}
+
+	if isEmpty(verification.Message) {
+		return ErrInvalidCredentials
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidCredentials, verification.Message)
+}
+
+// VerifyCredentials checks the given credentials against the DNSimple
+// API and asks the API which account they belong to. An error is only
+// returned if the verification itself failed (e.g. because the API is
+// unreachable). Rejected credentials are reported with
+// CredentialVerification.Valid.
+// The options are the same as for NewDNSClient.
+func VerifyCredentials(credentials Credentials, options ...ClientOption) (CredentialVerification, error) {
+	if accessTokenCredentials, ok := credentials.(AccessTokenCredentials); ok {
+		client, err := newV2ClientForCredentials(accessTokenCredentials, options...)
+		if err != nil {
+			return CredentialVerification{}, err
+		}
+
+		return verifyAccessToken(context.Background(), client)
+	}
+
+	client, err := newV1ClientForCredentials(credentials, options...)
+	if err != nil {
+		return CredentialVerification{}, err
+	}
+
+	return verifyCredentials(context.Background(), client, credentials)
+}
+
+// v1User is the response of the user endpoint of the DNSimple API v1.
+type v1User struct {
+	User struct {
+		ID    int    `json:"id"`
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+// verifyCredentials checks the given credentials with the given client.
+// The user endpoint identifies the account of account credentials.
+// Domain tokens cannot read the user, so they are checked against their
+// domain and the owner of the domain is reported as the account.
+func verifyCredentials(ctx context.Context, client *v1Client, credentials Credentials) (CredentialVerification, error) {
+	verification := CredentialVerification{Kind: credentials.Kind()}
+
+	if domainCredentials, ok := credentials.(DomainTokenCredentials); ok {
+		var domain dnsimple.DomainResponse
+		_, err := client.getPage(ctx, "/domains/"+normalizeDomain(domainCredentials.Domain), &domain)
+		if rejected, message := isRejectedCredentialsError(err); rejected {
+			// a domain token for an unknown domain is reported as not found
+			verification.Message = message
+			return verification, nil
+		} else if err != nil {
+			return verification, fmt.Errorf("Unable to verify credentials: %s", err.Error())
+		}
+
+		verification.Valid = true
+		verification.AccountID = domain.Domain.UserId
+		verification.Domains = []string{domain.Domain.Name}
+		return verification, nil
+	}
+
+	var user v1User
+	_, err := client.getPage(ctx, "/user", &user)
+	if rejected, message := isRejectedCredentialsError(err); rejected {
+		verification.Message = message
+		return verification, nil
+	} else if err != nil {
+		return verification, fmt.Errorf("Unable to verify credentials: %s", err.Error())
+	}
+
+	verification.AccountID = user.User.ID
+	verification.Email = user.User.Email
+
+	domains, err := client.GetDomainsContext(ctx)
+	if rejected, message := isRejectedCredentialsError(err); rejected {
+		verification.Message = message
+		return verification, nil
+	} else if err != nil {
+		return verification, fmt.Errorf("Unable to verify credentials: %s", err.Error())
+	}
+
+	verification.Valid = true
+	for _, domain := range domains {
+		verification.Domains = append(verification.Domains, domain.Name)
+	}
+
+	return verification, nil
+}
+
+// verifyAccessToken checks the credentials of the given API v2 client.
+// The whoami endpoint identifies the account and the domain list
+// ensures that the token can access the configured account.
+func verifyAccessToken(ctx context.Context, client *v2Client) (CredentialVerification, error) {
+	verification := CredentialVerification{Kind: AccessTokenCredentialKind}
+
+	whoami, err := client.whoami(ctx)
+	if rejected, message := isRejectedCredentialsError(err); rejected {
+		verification.Message = message
+		return verification, nil
+	} else if err != nil {
+		return verification, fmt.Errorf("Unable to verify credentials: %s", err.Error())
+	}
+
+	if whoami.Account != nil {
+		verification.AccountID = whoami.Account.ID
+		verification.Email = whoami.Account.Email
+	} else if whoami.User != nil {
+		verification.Email = whoami.User.Email
+	}
+
+	domains, err := client.GetDomainsContext(ctx)
+	if rejected, message := isRejectedCredentialsError(err); rejected {
+		verification.Message = message
+		return verification, nil
+	} else if err != nil {
+		return verification, fmt.Errorf("Unable to verify credentials: %s", err.Error())
+	}
+
+	verification.Valid = true
+	for _, domain := range domains {
+		verification.Domains = append(verification.Domains, domain.Name)
+	}
+
+	return verification, nil
+}
+
+// isRejectedCredentialsError returns true and the message of the API
+// if the given error shows that the API rejected the credentials.
+func isRejectedCredentialsError(err error) (bool, string) {
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		return false, ""
+	}
+
+	switch apiError.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		if isEmpty(apiError.Message) {
+			return true, apiError.Status
+		}
+
+		return true, apiError.Message
+	}
+
+	return false, ""
+}
@@ -0,0 +1,223 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestV1ClientForCredentials creates a v1 client for the given
+// credentials that sends all requests to the given test server.
+func newTestV1ClientForCredentials(credentials Credentials, server *httptest.Server) *v1Client {
+	client, _ := newV1ClientForCredentials(credentials, WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	return client
+}
+
+// Valid account credentials should be reported together with the account and its domains.
+func Test_verifyCredentials_APIAcceptsCredentials_VerificationIsValid(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-DNSimple-Token") != "John.Doe@example.com:ApItOken" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/user":
+			fmt.Fprint(w, `{"user": {"id": 42, "email": "john.doe@example.com"}}`)
+		case "/domains":
+			fmt.Fprint(w, `[{"domain": {"id": 1, "user_id": 42, "name": "example.com"}}, {"domain": {"id": 2, "user_id": 42, "name": "example.org"}}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	credentials := APICredentials{"John.Doe@example.com", "ApItOken"}
+
+	// act
+	verification, err := verifyCredentials(context.Background(), newTestV1ClientForCredentials(credentials, server), credentials)
+
+	// assert
+	if err != nil || !verification.Valid {
+		t.Fatalf("verifyCredentials() should report valid credentials but returned %+v, %v", verification, err)
+	}
+
+	if verification.AccountID != 42 || verification.Email != "john.doe@example.com" || len(verification.Domains) != 2 {
+		t.Fail()
+		t.Logf("verifyCredentials() returned an incomplete result: %+v", verification)
+	}
+}
+
+// The account should be reported even if it has no domains.
+func Test_verifyCredentials_AccountWithoutDomains_AccountIsReported(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			fmt.Fprint(w, `{"user": {"id": 42, "email": "john.doe@example.com"}}`)
+		case "/domains":
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	credentials := APICredentials{"john.doe@example.com", "ApItOken"}
+
+	// act
+	verification, err := verifyCredentials(context.Background(), newTestV1ClientForCredentials(credentials, server), credentials)
+
+	// assert
+	if err != nil || !verification.Valid || verification.AccountID != 42 || verification.Email != "john.doe@example.com" || len(verification.Domains) != 0 {
+		t.Fail()
+		t.Logf("verifyCredentials() should report the account without domains but returned %+v, %v", verification, err)
+	}
+}
+
+// Rejected credentials should be reported as invalid without returning an error.
+func Test_verifyCredentials_APIRejectsCredentials_VerificationIsInvalid(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Authentication failed"}`)
+	}))
+	defer server.Close()
+
+	credentials := APICredentials{"john.doe@example.com", "wrong"}
+
+	// act
+	verification, err := verifyCredentials(context.Background(), newTestV1ClientForCredentials(credentials, server), credentials)
+
+	// assert
+	if err != nil || verification.Valid {
+		t.Fatalf("verifyCredentials() should report invalid credentials but returned %+v, %v", verification, err)
+	}
+
+	if !errors.Is(verification.Err(), ErrInvalidCredentials) || verification.Message != "Authentication failed" {
+		t.Fail()
+		t.Logf("Err() should return ErrInvalidCredentials with the API message but returned %v", verification.Err())
+	}
+}
+
+// Domain tokens should be verified against their domain.
+func Test_verifyCredentials_DomainToken_DomainIsRequested(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/domains/example.com" || r.Header.Get("X-DNSimple-Domain-Token") != "DoMaInToKeN" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"domain": {"id": 1, "user_id": 42, "name": "example.com"}}`)
+	}))
+	defer server.Close()
+
+	credentials := DomainTokenCredentials{"example.com", "DoMaInToKeN"}
+
+	// act
+	verification, err := verifyCredentials(context.Background(), newTestV1ClientForCredentials(credentials, server), credentials)
+
+	// assert
+	if err != nil || !verification.Valid || len(verification.Domains) != 1 || verification.Domains[0] != "example.com" {
+		t.Fail()
+		t.Logf("verifyCredentials() should report the domain of the token but returned %+v, %v", verification, err)
+	}
+}
+
+// Server errors should be returned as errors because the credentials could not be verified.
+func Test_verifyCredentials_ServerError_ErrorIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	credentials := APICredentials{"john.doe@example.com", "ApItOken"}
+
+	// act
+	_, err := verifyCredentials(context.Background(), newTestV1ClientForCredentials(credentials, server), credentials)
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("verifyCredentials() should return an error if the API responds with a server error")
+	}
+}
+
+// Access tokens should be verified with the whoami endpoint and the domains of the account.
+func Test_verifyAccessToken_APIAcceptsToken_VerificationIsValid(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/whoami":
+			fmt.Fprint(w, `{"data": {"user": null, "account": {"id": 1010, "email": "john.doe@example.com"}}}`)
+		case "/1010/domains":
+			fmt.Fprint(w, `{"data": [{"id": 1, "account_id": 1010, "name": "example.com"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// act
+	verification, err := verifyAccessToken(context.Background(), newTestV2Client("", server))
+
+	// assert
+	if err != nil || !verification.Valid || verification.AccountID != 1010 || verification.Email != "john.doe@example.com" || len(verification.Domains) != 1 {
+		t.Fail()
+		t.Logf("verifyAccessToken() should report the account and its domains but returned %+v, %v", verification, err)
+	}
+}
+
+// The account of an access token should be reported even if it has no domains.
+func Test_verifyAccessToken_AccountWithoutDomains_AccountIsReported(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/whoami":
+			fmt.Fprint(w, `{"data": {"user": null, "account": {"id": 1010, "email": "john.doe@example.com"}}}`)
+		case "/1010/domains":
+			fmt.Fprint(w, `{"data": []}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// act
+	verification, err := verifyAccessToken(context.Background(), newTestV2Client("", server))
+
+	// assert
+	if err != nil || !verification.Valid || verification.AccountID != 1010 || len(verification.Domains) != 0 {
+		t.Fail()
+		t.Logf("verifyAccessToken() should report the account without domains but returned %+v, %v", verification, err)
+	}
+}
+
+// Rejected access tokens should be reported as invalid.
+func Test_verifyAccessToken_APIRejectsToken_VerificationIsInvalid(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Authentication failed"}`)
+	}))
+	defer server.Close()
+
+	// act
+	verification, err := verifyAccessToken(context.Background(), newTestV2Client("1010", server))
+
+	// assert
+	if err != nil || verification.Valid || verification.Message != "Authentication failed" || !errors.Is(verification.Err(), ErrInvalidCredentials) {
+		t.Fail()
+		t.Logf("verifyAccessToken() should report invalid credentials but returned %+v, %v", verification, err)
+	}
+}